@@ -1,12 +1,77 @@
 package main
 
 import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
 	cs "github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider"
 	"github.com/hashicorp/terraform/plugin"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := generate(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	plugin.Serve(&plugin.ServeOpts{
 		ProviderFunc: cs.Provider,
 	})
 }
+
+// generate writes cloudstack.tf and import.sh for the existing objects of an
+// account. The credentials come from the environment or the config file as
+// for the provider.
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: terraform-provider-cs generate [options]")
+		flags.PrintDefaults()
+	}
+	out := flags.String("out", ".", "directory to write cloudstack.tf and import.sh to")
+	providerArgs := map[string]*string{
+		"end_point":   flags.String("end-point", "", "API endpoint"),
+		"config_file": flags.String("config-file", "", "CloudMonkey config file"),
+		"profile":     flags.String("profile", "", "CloudMonkey profile"),
+		"account":     flags.String("account", "", "account whose objects are generated"),
+		"domain_id":   flags.String("domain-id", "", "domain of the account"),
+		"project_id":  flags.String("project-id", "", "project whose objects are generated"),
+	}
+	flags.Parse(args)
+
+	raw := make(map[string]interface{})
+	for k, v := range providerArgs {
+		if *v != "" {
+			raw[k] = *v
+		}
+	}
+
+	var hcl, script bytes.Buffer
+	if err := cs.Generate(raw, &hcl, &script); err != nil {
+		return err
+	}
+
+	if err := create(filepath.Join(*out, "cloudstack.tf"), hcl.Bytes(), 0644); err != nil {
+		return err
+	}
+	return create(filepath.Join(*out, "import.sh"), script.Bytes(), 0755)
+}
+
+// create writes a new file, refusing to overwrite an existing one.
+func create(name string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,272 @@
+package cloudstack
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const (
+	// defaultTimeout is used for the create, update and delete timeouts of
+	// every resource unless the timeouts block overrides it.
+	defaultTimeout = 10 * time.Minute
+
+	defaultJobTimeout = 10 * time.Minute
+)
+
+var defaultPollInterval = 2 * time.Second
+
+// asyncJob is an async job started by an operation.
+type asyncJob struct {
+	id       string
+	command  string
+	objectId string
+	started  time.Time
+	lastPoll time.Time
+}
+
+// operation bounds the async jobs started while a resource runs one of its
+// create, update or delete functions.
+type operation struct {
+	deadline     time.Time
+	timeout      time.Duration
+	pollInterval time.Duration
+	jobTimeout   time.Duration
+	stop         <-chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]*asyncJob
+	err  error
+}
+
+// start records the job id started by command. objectId is the id of the
+// object the job creates, if the response of command gave one.
+func (op *operation) start(id, command, objectId string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	now := time.Now()
+	op.jobs[id] = &asyncJob{
+		id:       id,
+		command:  command,
+		objectId: objectId,
+		started:  now,
+		lastPoll: now,
+	}
+	log.Printf("[DEBUG] Started async job %s (%s)", id, command)
+}
+
+func (op *operation) finish(id string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if job, ok := op.jobs[id]; ok {
+		log.Printf("[DEBUG] Finished async job %s (%s) in %s",
+			id, job.command, time.Since(job.started))
+		delete(op.jobs, id)
+	}
+}
+
+// pending returns the ids of the jobs which have not finished yet.
+func (op *operation) pending() []string {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	ids := make([]string, 0, len(op.jobs))
+	for id := range op.jobs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// pendingObject returns the id of the object created by the first job which
+// has not finished yet, or "" when there is none.
+func (op *operation) pendingObject() string {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	var first *asyncJob
+	for _, job := range op.jobs {
+		if job.objectId != "" && (first == nil || job.started.Before(first.started)) {
+			first = job
+		}
+	}
+	if first == nil {
+		return ""
+	}
+	return first.objectId
+}
+
+// fail records err as the reason the operation gave up and returns it.
+func (op *operation) fail(err error) error {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.err == nil {
+		op.err = err
+	}
+	return op.err
+}
+
+func (op *operation) failure() error {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	return op.err
+}
+
+// waitPoll blocks until the job may be polled again. It returns an error
+// when the job or the operation runs out of time, or when terraform is
+// interrupted, so that the client stops polling.
+func (op *operation) waitPoll(id string) error {
+	op.mu.Lock()
+	j, ok := op.jobs[id]
+	var job asyncJob
+	if ok {
+		job = *j
+	}
+	op.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	deadline := op.deadline
+	timeout := op.timeout
+	if op.jobTimeout > 0 && job.started.Add(op.jobTimeout).Before(deadline) {
+		deadline = job.started.Add(op.jobTimeout)
+		timeout = op.jobTimeout
+	}
+
+	next := job.lastPoll.Add(op.pollInterval)
+	if next.After(deadline) {
+		next = deadline
+	}
+
+	select {
+	case <-op.stop:
+		return op.fail(fmt.Errorf(
+			"Interrupted while waiting for async job %s (%s)", job.id, job.command))
+	case <-time.After(next.Sub(time.Now())):
+	}
+
+	if !time.Now().Before(deadline) {
+		return op.fail(fmt.Errorf(
+			"Timeout after %s while waiting for async job %s (%s)",
+			timeout, job.id, job.command))
+	}
+
+	op.mu.Lock()
+	j.lastPoll = time.Now()
+	op.mu.Unlock()
+
+	return nil
+}
+
+// asyncJobTransport watches the API calls of one operation. It records the
+// async jobs the calls start and paces the queryAsyncJobResult requests the
+// client sends while it waits for them.
+type asyncJobTransport struct {
+	op   *operation
+	base http.RoundTripper
+}
+
+func (t *asyncJobTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+	command := params.Get("command")
+
+	if command == "queryAsyncJobResult" {
+		if err := t.op.waitPoll(params.Get("jobid")); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := responseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	job := parseAsyncJob(body)
+	if job.JobId == "" {
+		return resp, nil
+	}
+
+	if command == "queryAsyncJobResult" {
+		if job.JobStatus != 0 {
+			t.op.finish(job.JobId)
+		}
+	} else {
+		t.op.start(job.JobId, command, job.Id)
+	}
+
+	return resp, nil
+}
+
+// withOperation returns a copy of c whose client gives up waiting for async
+// jobs once timeout has passed or terraform is interrupted.
+func (c *Config) withOperation(timeout time.Duration) (*Config, *operation) {
+	op := &operation{
+		deadline:     time.Now().Add(timeout),
+		timeout:      timeout,
+		pollInterval: c.PollInterval,
+		jobTimeout:   c.JobTimeout,
+		stop:         c.stop,
+		jobs:         map[string]*asyncJob{},
+	}
+
+	client := *c.client
+	wrapTransport(&client, func(base http.RoundTripper) http.RoundTripper {
+		return &asyncJobTransport{op: op, base: base}
+	})
+
+	config := *c
+	config.client = &client
+
+	return &config, op
+}
+
+// withTimeout wraps a create, update or delete function of a resource so
+// that the async jobs it waits for are bounded by the timeout named key.
+func withTimeout(key string, f func(*schema.ResourceData, interface{}) error) func(*schema.ResourceData, interface{}) error {
+	return func(d *schema.ResourceData, meta interface{}) error {
+		config, op := meta.(*Config).withOperation(d.Timeout(key))
+
+		err := f(d, config)
+
+		// Jobs still pending were given up on, so they no longer count
+		// against max_async_jobs.
+		if config.jobLimiter != nil {
+			for _, id := range op.pending() {
+				config.jobLimiter.release(id)
+			}
+		}
+
+		if err != nil {
+			if opErr := op.failure(); opErr != nil {
+				// The object whose creation was given up on keeps being
+				// created by CloudStack. Its id goes into the state so that
+				// terraform taints it instead of losing track of it.
+				if key == schema.TimeoutCreate && d.Id() == "" {
+					if id := op.pendingObject(); id != "" {
+						log.Printf("[WARN] Keeping %s in the state, its creation did not finish", id)
+						d.SetId(id)
+					}
+				}
+				return opErr
+			}
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,105 @@
+package cloudstack
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+// pendingJobServer starts every command as an async job that never finishes.
+func pendingJobServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		command := strings.ToLower(r.URL.Query().Get("command"))
+		if command == "queryasyncjobresult" {
+			fmt.Fprintf(w, `{"queryasyncjobresultresponse": {"jobid": "%s", "jobstatus": 0}}`,
+				r.URL.Query().Get("jobid"))
+			return
+		}
+		fmt.Fprintf(w, `{"%sresponse": {"id": "vm-1", "jobid": "job-1"}}`, command)
+	}))
+}
+
+func testConfig(t *testing.T, endpoint string) *Config {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := cloudstack.NewClient(u, "apikey", "secretkey", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Config{
+		PollInterval: 10 * time.Millisecond,
+		JobTimeout:   time.Minute,
+		client:       client,
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	server := pendingJobServer()
+	defer server.Close()
+
+	config, op := testConfig(t, server.URL).withOperation(50 * time.Millisecond)
+
+	param := cloudstack.NewDeployVirtualMachineParameter("so", "tmpl", "zone")
+	if _, err := config.client.DeployVirtualMachine(param); err == nil {
+		t.Fatal("DeployVirtualMachine succeeded, expected timeout")
+	}
+
+	err := op.failure()
+	if err == nil || !strings.Contains(err.Error(), "job-1") ||
+		!strings.Contains(err.Error(), "Timeout") {
+		t.Errorf("expected timeout error naming job-1, got %v", err)
+	}
+	if id := op.pendingObject(); id != "vm-1" {
+		t.Errorf("expected the pending object vm-1, got %q", id)
+	}
+}
+
+func TestJobTimeout(t *testing.T) {
+	server := pendingJobServer()
+	defer server.Close()
+
+	config := testConfig(t, server.URL)
+	config.JobTimeout = 50 * time.Millisecond
+	config, op := config.withOperation(time.Minute)
+
+	param := cloudstack.NewResizeVolumeParameter("vol-1")
+	if _, err := config.client.ResizeVolume(param); err == nil {
+		t.Fatal("ResizeVolume succeeded, expected timeout")
+	}
+
+	err := op.failure()
+	if err == nil || !strings.Contains(err.Error(), "Timeout after 50ms") {
+		t.Errorf("expected job timeout error, got %v", err)
+	}
+}
+
+func TestOperationInterrupt(t *testing.T) {
+	server := pendingJobServer()
+	defer server.Close()
+
+	stop := make(chan struct{})
+	config := testConfig(t, server.URL)
+	config.stop = stop
+	config, op := config.withOperation(time.Minute)
+
+	time.AfterFunc(50*time.Millisecond, func() { close(stop) })
+
+	param := cloudstack.NewDeployVirtualMachineParameter("so", "tmpl", "zone")
+	if _, err := config.client.DeployVirtualMachine(param); err == nil {
+		t.Fatal("DeployVirtualMachine succeeded, expected interrupt")
+	}
+
+	err := op.failure()
+	if err == nil || !strings.Contains(err.Error(), "Interrupted") ||
+		!strings.Contains(err.Error(), "job-1") {
+		t.Errorf("expected interrupt error naming job-1, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package cloudstack
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+// capabilities are the version and features of the cloud which resources
+// check, as reported by listCapabilities when the provider is configured.
+type capabilities struct {
+	version                 string
+	securityGroupsEnabled   bool
+	allowUserCreateProjects bool
+	kvmSnapshotEnabled      bool
+}
+
+// loadCapabilities calls listCapabilities, which also verifies that the
+// endpoint and the credentials work before any resource is touched.
+func (c *Config) loadCapabilities() error {
+	capability, err := c.client.ListCapabilities(cloudstack.NewListCapabilitiesParameter())
+	if err != nil {
+		return fmt.Errorf("Error verify endpoint and credentials with %s: %s", c.EndPoint, err)
+	}
+
+	c.capabilities = &capabilities{
+		version:                 capability.CloudStackVersion.String(),
+		securityGroupsEnabled:   capability.SecurityGroupsEnabled.Bool(),
+		allowUserCreateProjects: capability.AllowUserCreateProjects.Bool(),
+		kvmSnapshotEnabled:      capability.KvmSnapshotEnabled.Bool(),
+	}
+	log.Printf("[INFO] CloudStack %s: security groups %t, user projects %t, KVM snapshots %t",
+		c.capabilities.version, c.capabilities.securityGroupsEnabled,
+		c.capabilities.allowUserCreateProjects, c.capabilities.kvmSnapshotEnabled)
+
+	return nil
+}
+
+// requireVersion returns an error unless the cloud runs version min or later.
+// It passes when the version is unknown.
+func (c *Config) requireVersion(feature, min string) error {
+	if c.capabilities == nil || c.capabilities.version == "" {
+		return nil
+	}
+	if compareVersions(c.capabilities.version, min) < 0 {
+		return fmt.Errorf("%s requires CloudStack %s or later, but %s runs %s",
+			feature, min, c.EndPoint, c.capabilities.version)
+	}
+	return nil
+}
+
+// requireSecurityGroups returns an error when security groups are not
+// enabled in the cloud.
+func (c *Config) requireSecurityGroups(feature string) error {
+	if c.capabilities == nil || c.capabilities.securityGroupsEnabled {
+		return nil
+	}
+	return fmt.Errorf("%s requires security groups, which are not enabled in %s",
+		feature, c.EndPoint)
+}
+
+// requireProjects returns an error when users can't create projects in the
+// cloud, which is how it tells whether they work with projects at all.
+func (c *Config) requireProjects(feature string) error {
+	if c.capabilities == nil || c.capabilities.allowUserCreateProjects {
+		return nil
+	}
+	return fmt.Errorf("%s requires projects, which are not enabled for users in %s",
+		feature, c.EndPoint)
+}
+
+// requireKVMSnapshots returns an error when snapshots of volumes of running
+// KVM virtual machines are not enabled in the cloud.
+func (c *Config) requireKVMSnapshots(feature string) error {
+	if c.capabilities == nil || c.capabilities.kvmSnapshotEnabled {
+		return nil
+	}
+	return fmt.Errorf("%s requires KVM snapshots, which are not enabled in %s",
+		feature, c.EndPoint)
+}
+
+// compareVersions compares dotted versions like 4.11.1.0 number by number,
+// ignoring suffixes like -SNAPSHOT.
+func compareVersions(a, b string) int {
+	as, bs := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionNumbers(version string) []int {
+	if i := strings.IndexAny(version, "-_ "); i >= 0 {
+		version = version[:i]
+	}
+	var numbers []int
+	for _, s := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
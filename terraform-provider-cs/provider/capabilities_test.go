@@ -0,0 +1,99 @@
+package cloudstack
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"4.11.1.0", "4.2", 1},
+		{"4.2.0", "4.2", 0},
+		{"4.1.1", "4.2", -1},
+		{"4.9.3.0-SNAPSHOT", "4.9.3", 0},
+		{"3.0.7", "4.0", -1},
+	}
+	for _, c := range cases {
+		if got := compareVersions(c.a, c.b); got != c.want {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestLoadCapabilities(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+	server.SetCapability("cloudstackversion", "4.1.1")
+
+	config := &Config{
+		EndPoint:  server.EndPoint(),
+		ApiKey:    server.APIKey,
+		SecretKey: server.SecretKey,
+	}
+	if err := config.loadAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+	if config.capabilities.version != "4.1.1" {
+		t.Errorf("version is %q, expected 4.1.1", config.capabilities.version)
+	}
+	if err := config.requireVersion("Resizing a volume", "4.2"); err == nil ||
+		!strings.Contains(err.Error(), "requires CloudStack 4.2 or later") {
+		t.Errorf("expected a version error, got %v", err)
+	}
+
+	if err := config.requireKVMSnapshots("Snapshots of running virtual machines"); err == nil ||
+		!strings.Contains(err.Error(), "requires KVM snapshots") {
+		t.Errorf("expected a KVM snapshots error, got %v", err)
+	}
+
+	server.SetCapability("allowusercreateprojects", false)
+	config = &Config{
+		EndPoint:  server.EndPoint(),
+		ApiKey:    server.APIKey,
+		SecretKey: server.SecretKey,
+		ProjectId: server.All("project")[0]["id"].(string),
+	}
+	if err := config.loadAndValidate(); err == nil ||
+		!strings.Contains(err.Error(), "project_id requires projects") {
+		t.Errorf("expected a projects error, got %v", err)
+	}
+
+	for _, keys := range [][2]string{{"wrong", server.SecretKey}, {server.APIKey, "wrong"}} {
+		config = &Config{
+			EndPoint:  server.EndPoint(),
+			ApiKey:    keys[0],
+			SecretKey: keys[1],
+		}
+		if err := config.loadAndValidate(); err == nil ||
+			!strings.Contains(err.Error(), "unable to verify user credentials") {
+			t.Errorf("expected a credentials error for keys %v, got %v", keys, err)
+		}
+	}
+}
+
+func TestAccSecurityGroup_disabled(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+	server.SetCapability("securitygroupsenabled", false)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_security_group" "foo" {
+  name = "sg01"
+}
+`),
+				ExpectError: regexp.MustCompile("requires security groups"),
+			},
+		},
+	})
+}
@@ -2,10 +2,13 @@ package cloudstack
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
+	"time"
 
 	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/mitchellh/go-homedir"
 )
 
 // Config is the configuration structure used to instantiate the CloudStack
@@ -15,31 +18,168 @@ type Config struct {
 	ApiKey    string
 	SecretKey string
 
-	client *cloudstack.Client
+	// Username, Password and Domain log in with a session for users
+	// without API keys.
+	Username string
+	Password string
+	Domain   string
+
+	// ConfigFile and Profile select a CloudMonkey profile. A Profile given
+	// explicitly provides the endpoint and all of the credentials, while
+	// the default profile of ConfigFile only provides those not given in
+	// the provider block or the environment.
+	ConfigFile string
+	Profile    string
+
+	// CAFile, ClientCertFile and ClientKeyFile are PEM files used to verify
+	// the management server and to authenticate to it. ProxyURL overrides
+	// the proxy from the environment and RequestTimeout bounds each HTTP
+	// request, not counting retries or the waits between job polls.
+	CAFile             string
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+	ProxyURL           string
+	RequestTimeout     time.Duration
+
+	// PollInterval is the interval between queryAsyncJobResult calls and
+	// JobTimeout bounds the wait for a single async job.
+	PollInterval time.Duration
+	JobTimeout   time.Duration
+
+	// MaxRetries is how many times a call failing with a transient error
+	// is retried.
+	MaxRetries int
+
+	// RequestsPerSecond limits the rate of API calls and MaxAsyncJobs the
+	// number of async jobs running at once. Zero means no limit.
+	RequestsPerSecond float64
+	MaxAsyncJobs      int
+
+	// TraceFile receives every API call as a JSON line.
+	TraceFile string
+
+	// Account, DomainId and ProjectId are the default owner of the
+	// resources which don't set their own.
+	Account   string
+	DomainId  string
+	ProjectId string
+
+	client       *cloudstack.Client
+	jobLimiter   *asyncJobLimiter
+	capabilities *capabilities
+
+	// stop is closed when terraform is interrupted.
+	stop <-chan struct{}
 }
 
 func (c *Config) loadAndValidate() error {
 
+	if err := c.loadCredentials(); err != nil {
+		return err
+	}
 	if c.EndPoint == "" {
-		c.EndPoint = os.Getenv("CLOUDSTACK_ENDPOINT")
+		return fmt.Errorf("end_point is not specified")
+	}
+	useSession := c.ApiKey == "" && c.SecretKey == ""
+	if useSession && (c.Username == "" || c.Password == "") {
+		return fmt.Errorf("api_key and secret_key, or username and password are not specified")
+	}
+	if !useSession && (c.ApiKey == "" || c.SecretKey == "") {
+		return fmt.Errorf("api_key and secret_key are not specified")
+	}
+	if c.Account != "" && c.DomainId == "" {
+		return fmt.Errorf("domain_id is required with account")
+	}
+	if c.ProjectId != "" && c.Account != "" {
+		return fmt.Errorf("account and project_id are mutually exclusive")
 	}
-	if c.ApiKey == "" {
-		c.ApiKey = os.Getenv("CLOUDSTACK_APIKEY")
+	if c.PollInterval == 0 {
+		c.PollInterval = defaultPollInterval
 	}
-	if c.SecretKey == "" {
-		c.SecretKey = os.Getenv("CLOUDSTACK_SECRETKEY")
+	if c.JobTimeout == 0 {
+		c.JobTimeout = defaultJobTimeout
 	}
 
 	endpoint, err := url.Parse(c.EndPoint)
 	if err != nil {
-		fmt.Errorf("Error parse endpoint (%s): %s",
+		return fmt.Errorf("Error parse endpoint (%s): %s",
 			c.EndPoint, err)
 	}
 
 	c.client, err = cloudstack.NewClient(endpoint, c.ApiKey, c.SecretKey, "", "")
 	if err != nil {
-		fmt.Errorf("Error failed to create new client. %s", err)
+		return fmt.Errorf("Error failed to create new client. %s", err)
 	}
 
+	c.client.HTTPClient, err = c.newHTTPClient()
+	if err != nil {
+		return err
+	}
+
+	// The trace sits right above the HTTP client so that it records every
+	// request sent, including logins and retries.
+	trace := &traceTransport{}
+	if c.TraceFile != "" {
+		path, err := homedir.Expand(c.TraceFile)
+		if err != nil {
+			return fmt.Errorf("Error expand trace file path %s: %s", c.TraceFile, err)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("Error open trace file: %s", err)
+		}
+		trace.out = f
+		if c.stop != nil {
+			go func() {
+				<-c.stop
+				trace.close()
+			}()
+		}
+	}
+	wrapTransport(c.client, func(base http.RoundTripper) http.RoundTripper {
+		trace.base = base
+		return trace
+	})
+
+	if useSession {
+		wrapTransport(c.client, func(base http.RoundTripper) http.RoundTripper {
+			return &sessionTransport{
+				endPoint: endpoint,
+				username: c.Username,
+				password: c.Password,
+				domain:   c.Domain,
+				base:     base,
+			}
+		})
+	}
+
+	if c.RequestsPerSecond > 0 {
+		wrapTransport(c.client, func(base http.RoundTripper) http.RoundTripper {
+			return newRateLimitTransport(c.RequestsPerSecond, c.stop, base)
+		})
+	}
+
+	wrapTransport(c.client, func(base http.RoundTripper) http.RoundTripper {
+		return &retryTransport{maxRetries: c.MaxRetries, stop: c.stop, base: base}
+	})
+
+	if c.MaxAsyncJobs > 0 {
+		c.jobLimiter = newAsyncJobLimiter(c.MaxAsyncJobs, c.stop)
+		wrapTransport(c.client, func(base http.RoundTripper) http.RoundTripper {
+			return &asyncJobLimitTransport{limiter: c.jobLimiter, base: base}
+		})
+	}
+
+	if err := c.loadCapabilities(); err != nil {
+		trace.close()
+		return err
+	}
+	if c.ProjectId != "" {
+		if err := c.requireProjects("project_id"); err != nil {
+			trace.close()
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,175 @@
+package cloudstack
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// defaultConfigFile is where CloudMonkey keeps its profiles.
+const defaultConfigFile = "~/.cloudmonkey/config"
+
+// parseConfigFile reads a CloudMonkey style INI file and returns the keys of
+// each section. Section and key names are case sensitive like in CloudMonkey.
+func parseConfigFile(r io.Reader) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{}
+	var section map[string]string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := sections[name]; !ok {
+				sections[name] = map[string]string{}
+			}
+			section = sections[name]
+			continue
+		}
+
+		i := strings.IndexAny(line, "=:")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value: %s", n, line)
+		}
+		if section == nil {
+			return nil, fmt.Errorf("line %d: key outside of a section: %s", n, line)
+		}
+		section[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// credentialEnv maps the endpoint and credential arguments of the provider
+// to the environment variables setting them.
+var credentialEnv = []struct {
+	arg, env string
+}{
+	{"end_point", "CLOUDSTACK_ENDPOINT"},
+	{"api_key", "CLOUDSTACK_APIKEY"},
+	{"secret_key", "CLOUDSTACK_SECRETKEY"},
+	{"username", "CLOUDSTACK_USERNAME"},
+	{"password", "CLOUDSTACK_PASSWORD"},
+	{"domain", "CLOUDSTACK_DOMAIN"},
+}
+
+// credentials returns the fields of the endpoint and credential arguments,
+// in the order of credentialEnv.
+func (c *Config) credentials() []*string {
+	return []*string{&c.EndPoint, &c.ApiKey, &c.SecretKey, &c.Username, &c.Password, &c.Domain}
+}
+
+// loadCredentials fills the endpoint and credentials from the environment
+// and the config file. A profile selected with profile or
+// CLOUDSTACK_PROFILE provides all of them, so that none is mixed up with
+// the ones of another cloud: the provider block must not set them and the
+// environment is ignored. Otherwise each one is taken from the provider
+// block, then the environment and finally the default profile.
+func (c *Config) loadCredentials() error {
+	if c.ConfigFile == "" {
+		c.ConfigFile = os.Getenv("CLOUDSTACK_CONFIG")
+	}
+	if c.Profile == "" {
+		c.Profile = os.Getenv("CLOUDSTACK_PROFILE")
+	}
+
+	fields := c.credentials()
+	if c.Profile != "" {
+		var args []string
+		for i, v := range credentialEnv {
+			if *fields[i] != "" {
+				args = append(args, v.arg)
+			}
+			if os.Getenv(v.env) != "" {
+				log.Printf("[WARN] Ignoring %s, profile %s is selected", v.env, c.Profile)
+			}
+		}
+		if len(args) > 0 {
+			return fmt.Errorf("%s cannot be combined with profile %s, which provides the endpoint and credentials",
+				strings.Join(args, ", "), c.Profile)
+		}
+	} else {
+		for i, v := range credentialEnv {
+			if *fields[i] == "" {
+				*fields[i] = os.Getenv(v.env)
+			}
+		}
+	}
+
+	return c.loadProfile()
+}
+
+// loadProfile fills the settings left empty by the provider block and the
+// environment from a profile of the config file. Without a profile the one
+// named in the [core] section is used.
+func (c *Config) loadProfile() error {
+	if c.ConfigFile == "" && c.Profile == "" {
+		return nil
+	}
+
+	path := c.ConfigFile
+	if path == "" {
+		path = defaultConfigFile
+	}
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return fmt.Errorf("Error expand config file path %s: %s", c.ConfigFile, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error open config file: %s", err)
+	}
+	defer f.Close()
+
+	sections, err := parseConfigFile(f)
+	if err != nil {
+		return fmt.Errorf("Error parse config file %s: %s", path, err)
+	}
+
+	profile := c.Profile
+	if profile == "" {
+		profile = sections["core"]["profile"]
+	}
+	if profile == "" {
+		return fmt.Errorf("profile is not specified and %s has no default profile", path)
+	}
+
+	section, ok := sections[profile]
+	if !ok {
+		return fmt.Errorf("Profile %s is not found in %s", profile, path)
+	}
+
+	if c.EndPoint == "" {
+		c.EndPoint = section["url"]
+	}
+	if c.ApiKey == "" {
+		c.ApiKey = section["apikey"]
+	}
+	if c.SecretKey == "" {
+		c.SecretKey = section["secretkey"]
+	}
+	if c.Username == "" {
+		c.Username = section["username"]
+	}
+	if c.Password == "" {
+		c.Password = section["password"]
+	}
+	if c.Domain == "" {
+		c.Domain = section["domain"]
+	}
+
+	return nil
+}
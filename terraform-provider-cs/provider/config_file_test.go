@@ -0,0 +1,110 @@
+package cloudstack
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `
+[core]
+profile = dev
+asyncblock = true
+
+; comment
+[dev]
+url = http://dev.example.com:8080/client/api
+apikey = dev-apikey
+secretkey = dev-secretkey
+
+[prod]
+url = https://prod.example.com/client/api
+apikey = prod-apikey
+secretkey = prod-secretkey
+`
+
+func TestParseConfigFile(t *testing.T) {
+	sections, err := parseConfigFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sections["core"]["profile"] != "dev" {
+		t.Errorf("core profile is %q, expected dev", sections["core"]["profile"])
+	}
+	if sections["prod"]["url"] != "https://prod.example.com/client/api" {
+		t.Errorf("prod url is %q", sections["prod"]["url"])
+	}
+
+	if _, err := parseConfigFile(strings.NewReader("url = x\n")); err == nil {
+		t.Errorf("expected error for a key outside of a section")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	f, err := ioutil.TempFile("", "cloudmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testConfigFile)
+	f.Close()
+
+	c := &Config{ConfigFile: f.Name()}
+	if err := c.loadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if c.ApiKey != "dev-apikey" {
+		t.Errorf("default profile: api key is %q, expected dev-apikey", c.ApiKey)
+	}
+
+	c = &Config{ConfigFile: f.Name(), Profile: "prod"}
+	if err := c.loadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if c.ApiKey != "prod-apikey" || c.SecretKey != "prod-secretkey" {
+		t.Errorf("keys are %q and %q, expected the ones of prod", c.ApiKey, c.SecretKey)
+	}
+
+	c = &Config{ConfigFile: f.Name(), Profile: "staging"}
+	if err := c.loadProfile(); err == nil {
+		t.Errorf("expected error for an unknown profile")
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	f, err := ioutil.TempFile("", "cloudmonkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testConfigFile)
+	f.Close()
+
+	os.Setenv("CLOUDSTACK_APIKEY", "env-apikey")
+	defer os.Unsetenv("CLOUDSTACK_APIKEY")
+
+	// The default profile only fills what the environment leaves empty.
+	c := &Config{ConfigFile: f.Name()}
+	if err := c.loadCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if c.ApiKey != "env-apikey" || c.SecretKey != "dev-secretkey" {
+		t.Errorf("keys are %q and %q, expected env-apikey and dev-secretkey", c.ApiKey, c.SecretKey)
+	}
+
+	// An explicit profile ignores the environment.
+	c = &Config{ConfigFile: f.Name(), Profile: "prod"}
+	if err := c.loadCredentials(); err != nil {
+		t.Fatal(err)
+	}
+	if c.ApiKey != "prod-apikey" || c.EndPoint != "https://prod.example.com/client/api" {
+		t.Errorf("api key is %q and end point %q, expected the ones of prod", c.ApiKey, c.EndPoint)
+	}
+
+	// and can't be mixed with the provider block.
+	c = &Config{ConfigFile: f.Name(), Profile: "prod", ApiKey: "explicit"}
+	if err := c.loadCredentials(); err == nil || !strings.Contains(err.Error(), "api_key") {
+		t.Errorf("expected an error naming api_key, got %v", err)
+	}
+}
@@ -0,0 +1,1912 @@
+package cstest
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+// owner is the account owning the objects created through the API.
+var owner = Object{
+	"account":  "admin",
+	"domainid": "b47ae2b4-1e0a-4c3b-9c5e-2a1c9bde7a10",
+	"domain":   "ROOT",
+}
+
+func owned(obj Object) Object {
+	for k, v := range owner {
+		obj[k] = v
+	}
+	obj["tags"] = []Object{}
+	return obj
+}
+
+// ownerFields are the fields naming the owner of an object.
+var ownerFields = []string{"account", "domainid", "domain", "projectid", "project"}
+
+// setOwner gives obj to the project or account named in params, like the
+// create commands accepting projectid or account and domainid do.
+func (s *Server) setOwner(obj Object, params url.Values) error {
+	if params.Get("projectid") != "" {
+		project, err := s.mustGet("project", params, "projectid")
+		if err != nil {
+			return err
+		}
+		delete(obj, "account")
+		obj["projectid"] = project["id"]
+		obj["project"] = project["name"]
+		obj["domainid"] = project["domainid"]
+		obj["domain"] = project["domain"]
+		return nil
+	}
+
+	if params.Get("account") != "" {
+		if params.Get("domainid") == "" {
+			return Errorf("Unable to execute API command due to missing parameter domainid, which is required with account")
+		}
+		obj["account"] = params.Get("account")
+		obj["domainid"] = params.Get("domainid")
+		obj["domain"] = ""
+		for _, domain := range s.objects["domain"] {
+			if domain["id"] == obj["domainid"] {
+				obj["domain"] = domain["name"]
+			}
+		}
+	}
+	return nil
+}
+
+// inherit gives obj the owner of from, as rules belong to the owner of
+// their IP address.
+func inherit(obj, from Object) Object {
+	for _, k := range ownerFields {
+		if v, ok := from[k]; ok {
+			obj[k] = v
+		} else {
+			delete(obj, k)
+		}
+	}
+	return obj
+}
+
+// seed adds the infrastructure every test can rely on.
+func seed(s *Server) {
+	s.add("capability", Object{
+		"id":                        "capability",
+		"cloudstackversion":         "4.11.1.0",
+		"securitygroupsenabled":     true,
+		"allowusercreateprojects":   true,
+		"projectinviterequired":     false,
+		"kvmsnapshotenabled":        false,
+		"userpublictemplateenabled": true,
+		"customdiskofferingmaxsize": 1024,
+		"apilimitinterval":          1,
+		"apilimitmax":               0,
+	})
+	root := s.add("domain", Object{
+		"id":    owner["domainid"],
+		"name":  owner["domain"],
+		"path":  owner["domain"],
+		"level": 0,
+	})
+	s.add("domain", Object{
+		"name":             "dev",
+		"path":             "ROOT/dev",
+		"parentdomainid":   root["id"],
+		"parentdomainname": root["name"],
+		"level":            1,
+	})
+	for _, name := range []string{"admin", "user1"} {
+		s.add("account", Object{
+			"name":        name,
+			"accounttype": 0,
+			"domainid":    root["id"],
+			"domain":      root["name"],
+			"state":       "enabled",
+		})
+	}
+	for _, typ := range []string{"host anti-affinity", "host affinity"} {
+		s.add("affinitygrouptype", Object{"type": typ})
+	}
+	s.add("project", Object{
+		"name":        "project1",
+		"displaytext": "Test project",
+		"account":     owner["account"],
+		"domainid":    owner["domainid"],
+		"domain":      owner["domain"],
+		"state":       "Active",
+		"tags":        []Object{},
+	})
+
+	zone := s.add("zone", Object{
+		"name":                  "zone1",
+		"description":           "Test zone",
+		"networktype":           "Advanced",
+		"securitygroupsenabled": false,
+		"dns1":                  "8.8.8.8",
+		"dns2":                  "8.8.4.4",
+		"internaldns1":          "10.0.0.1",
+		"domain":                "cs.internal",
+		"allocationstate":       "Enabled",
+		"localstorageenabled":   false,
+	})
+
+	pod := s.add("pod", Object{
+		"name":            "pod1",
+		"zoneid":          zone["id"],
+		"zonename":        zone["name"],
+		"gateway":         "10.0.0.1",
+		"netmask":         "255.255.255.0",
+		"allocationstate": "Enabled",
+	})
+	cluster := s.add("cluster", Object{
+		"name":            "cluster1",
+		"zoneid":          zone["id"],
+		"zonename":        zone["name"],
+		"podid":           pod["id"],
+		"podname":         pod["name"],
+		"hypervisortype":  "KVM",
+		"clustertype":     "CloudManaged",
+		"allocationstate": "Enabled",
+	})
+	s.add("host", Object{
+		"name":        "host1",
+		"zoneid":      zone["id"],
+		"zonename":    zone["name"],
+		"podid":       pod["id"],
+		"podname":     pod["name"],
+		"clusterid":   cluster["id"],
+		"clustername": cluster["name"],
+		"type":        "Routing",
+		"state":       "Up",
+		"ipaddress":   "10.0.0.11",
+		"hypervisor":  "KVM",
+	})
+
+	s.add("serviceoffering", Object{
+		"name":         "small",
+		"displaytext":  "1 vCPU, 512 MB",
+		"cpunumber":    1,
+		"cpuspeed":     500,
+		"memory":       512,
+		"storagetype":  "shared",
+		"iscustomized": false,
+		"offerha":      false,
+		"limitcpuuse":  false,
+	})
+	s.add("serviceoffering", Object{
+		"name":         "medium",
+		"displaytext":  "2 vCPU, 2 GB",
+		"cpunumber":    2,
+		"cpuspeed":     1000,
+		"memory":       2048,
+		"storagetype":  "shared",
+		"iscustomized": false,
+		"offerha":      false,
+		"limitcpuuse":  false,
+	})
+
+	s.add("diskoffering", Object{
+		"name":         "small",
+		"displaytext":  "5 GB",
+		"disksize":     5,
+		"iscustomized": false,
+		"storagetype":  "shared",
+	})
+	s.add("diskoffering", Object{
+		"name":         "custom",
+		"displaytext":  "Custom size",
+		"disksize":     0,
+		"iscustomized": true,
+		"storagetype":  "shared",
+	})
+
+	s.add("networkoffering", Object{
+		"name":        "DefaultIsolatedNetworkOfferingWithSourceNatService",
+		"displaytext": "Offering for Isolated networks with Source Nat service enabled",
+		"state":       "Enabled",
+		"guestiptype": "Isolated",
+		"forvpc":      false,
+	})
+	s.add("physicalnetwork", Object{
+		"name":   "physnet1",
+		"zoneid": zone["id"],
+		"state":  "Enabled",
+	})
+	s.add("networkoffering", Object{
+		"name":        "DefaultIsolatedNetworkOfferingForVpcNetworks",
+		"displaytext": "Offering for Isolated VPC networks with Source Nat service enabled",
+		"state":       "Enabled",
+		"guestiptype": "Isolated",
+		"forvpc":      true,
+	})
+
+	s.add("template", Object{
+		"name":            "CentOS 7",
+		"displaytext":     "CentOS 7 x86_64",
+		"zoneid":          zone["id"],
+		"zonename":        zone["name"],
+		"isready":         true,
+		"isfeatured":      true,
+		"ispublic":        true,
+		"templatetype":    "USER",
+		"hypervisor":      "KVM",
+		"ostypeid":        "a8e5f4d1-5b7c-4c3f-9f3e-2d1c0b9a8f7e",
+		"ostypename":      "CentOS 7",
+		"size":            10 * gigabyte,
+		"passwordenabled": true,
+		"sshkeyenabled":   true,
+		"checksum":        "9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d",
+		"created":         "2016-05-01T10:00:00+0000",
+		"format":          "QCOW2",
+		"account":         "system",
+		"domain":          "ROOT",
+		"tags":            []Object{},
+	})
+
+	s.add("iso", Object{
+		"name":        "TinyLinux",
+		"displaytext": "TinyLinux rescue ISO",
+		"zoneid":      zone["id"],
+		"zonename":    zone["name"],
+		"isready":     true,
+		"bootable":    true,
+		"ispublic":    true,
+		"isfeatured":  true,
+		"ostypename":  "Other Linux (64-bit)",
+		"account":     "system",
+		"domain":      "ROOT",
+		"tags":        []Object{},
+	})
+
+	s.add("vpcoffering", Object{
+		"name":            "Default VPC offering",
+		"displaytext":     "Default VPC offering",
+		"state":           "Enabled",
+		"isdefault":       true,
+		"redundantrouter": false,
+	})
+	s.add("vpcoffering", Object{
+		"name":            "Redundant VPC offering",
+		"displaytext":     "Redundant VPC offering",
+		"state":           "Enabled",
+		"isdefault":       false,
+		"redundantrouter": true,
+	})
+	for _, name := range []string{"default_allow", "default_deny"} {
+		s.add("networkacllist", Object{
+			"name":        name,
+			"description": strings.Replace(name, "_", " ", -1) + " rule",
+		})
+	}
+}
+
+func registerHandlers(s *Server) {
+	for command, kind := range map[string]string{
+		"listZones":               "zone",
+		"listServiceOfferings":    "serviceoffering",
+		"listDiskOfferings":       "diskoffering",
+		"listNetworkOfferings":    "networkoffering",
+		"listNetworks":            "network",
+		"listPublicIpAddresses":   "publicipaddress",
+		"listVolumes":             "volume",
+		"listFirewallRules":       "firewallrule",
+		"listPortForwardingRules": "portforwardingrule",
+		"listLoadBalancerRules":   "loadbalancerrule",
+		"listVPCs":                "vpc",
+		"listVPCOfferings":        "vpcoffering",
+		"listProjects":            "project",
+		"listDomains":             "domain",
+		"listAccounts":            "account",
+		"listAffinityGroups":      "affinitygroup",
+		"listSSHKeyPairs":         "sshkeypair",
+		"listIsos":                "iso",
+		"listPods":                "pod",
+		"listClusters":            "cluster",
+		"listHosts":               "host",
+		"listSnapshots":           "snapshot",
+		"listNetworkACLLists":     "networkacllist",
+		"listNetworkACLs":         "networkacl",
+		"listPhysicalNetworks":    "physicalnetwork",
+		"listPrivateGateways":     "privategateway",
+		"listStaticRoutes":        "staticroute",
+	} {
+		s.handlers[strings.ToLower(command)] = handler{fn: listHandler(kind)}
+	}
+
+	for command, h := range map[string]handler{
+		"listCapabilities":              {fn: listCapabilities},
+		"listTemplates":                 {fn: listTemplates},
+		"listVirtualMachines":           {fn: listVirtualMachines},
+		"deployVirtualMachine":          {async: true, fn: deployVirtualMachine},
+		"updateVirtualMachine":          {fn: updateVirtualMachine},
+		"getVirtualMachineUserData":     {fn: getVirtualMachineUserData},
+		"destroyVirtualMachine":         {async: true, fn: destroyVirtualMachine},
+		"startVirtualMachine":           {async: true, fn: setVirtualMachineState("Running")},
+		"stopVirtualMachine":            {async: true, fn: setVirtualMachineState("Stopped")},
+		"createNetwork":                 {fn: createNetwork},
+		"replaceNetworkACLList":         {async: true, fn: replaceNetworkACLList},
+		"createNetworkACLList":          {async: true, fn: createNetworkACLList},
+		"deleteNetworkACLList":          {async: true, fn: deleteNetworkACLList},
+		"createNetworkACL":              {async: true, fn: createNetworkACL},
+		"updateNetworkACLItem":          {async: true, fn: updateNetworkACLItem},
+		"deleteNetworkACL":              {async: true, fn: deleteNetworkACL},
+		"createPrivateGateway":          {async: true, fn: createPrivateGateway},
+		"deletePrivateGateway":          {async: true, fn: deletePrivateGateway},
+		"createStaticRoute":             {async: true, fn: createStaticRoute},
+		"deleteStaticRoute":             {async: true, fn: deleteStaticRoute},
+		"createVPC":                     {async: true, fn: createVPC},
+		"updateVPC":                     {async: true, fn: updateVPC},
+		"restartVPC":                    {async: true, fn: restartVPC},
+		"deleteVPC":                     {async: true, fn: deleteVPC},
+		"updateNetwork":                 {async: true, fn: updateNetwork},
+		"deleteNetwork":                 {async: true, fn: deleteNetwork},
+		"associateIpAddress":            {async: true, fn: associateIpAddress},
+		"disassociateIpAddress":         {async: true, fn: disassociateIpAddress},
+		"enableStaticNat":               {fn: enableStaticNat},
+		"disableStaticNat":              {async: true, fn: disableStaticNat},
+		"createVolume":                  {async: true, fn: createVolume},
+		"attachVolume":                  {async: true, fn: attachVolume},
+		"detachVolume":                  {async: true, fn: detachVolume},
+		"resizeVolume":                  {async: true, fn: resizeVolume},
+		"deleteVolume":                  {fn: deleteVolume},
+		"createFirewallRule":            {async: true, fn: createFirewallRule},
+		"deleteFirewallRule":            {async: true, fn: deleteHandler("firewallrule")},
+		"createPortForwardingRule":      {async: true, fn: createPortForwardingRule},
+		"deletePortForwardingRule":      {async: true, fn: deleteHandler("portforwardingrule")},
+		"createLoadBalancerRule":        {async: true, fn: createLoadBalancerRule},
+		"updateLoadBalancerRule":        {async: true, fn: updateLoadBalancerRule},
+		"deleteLoadBalancerRule":        {async: true, fn: deleteHandler("loadbalancerrule")},
+		"assignToLoadBalancerRule":      {async: true, fn: assignToLoadBalancerRule},
+		"removeFromLoadBalancerRule":    {async: true, fn: removeFromLoadBalancerRule},
+		"listLoadBalancerRuleInstances": {fn: listLoadBalancerRuleInstances},
+		"createSecurityGroup":           {fn: createSecurityGroup},
+		"deleteSecurityGroup":           {fn: deleteSecurityGroup},
+		"listSecurityGroups":            {fn: listSecurityGroups},
+		"authorizeSecurityGroupIngress": {async: true, fn: authorizeSecurityGroupRule("ingressrule")},
+		"authorizeSecurityGroupEgress":  {async: true, fn: authorizeSecurityGroupRule("egressrule")},
+		"revokeSecurityGroupIngress":    {async: true, fn: revokeSecurityGroupRule("ingressrule")},
+		"revokeSecurityGroupEgress":     {async: true, fn: revokeSecurityGroupRule("egressrule")},
+		"listAffinityGroupTypes":        {fn: listHandler("affinitygrouptype")},
+		"createAffinityGroup":           {async: true, fn: createAffinityGroup},
+		"deleteAffinityGroup":           {async: true, fn: deleteAffinityGroup},
+		"updateVMAffinityGroup":         {async: true, fn: updateVMAffinityGroup},
+		"createSSHKeyPair":              {fn: createSSHKeyPair},
+		"registerSSHKeyPair":            {fn: registerSSHKeyPair},
+		"deleteSSHKeyPair":              {fn: deleteSSHKeyPair},
+	} {
+		s.handlers[strings.ToLower(command)] = h
+	}
+}
+
+func listCapabilities(s *Server, params url.Values) (Object, error) {
+	capability := Object{}
+	for k, v := range s.get("capability", "capability") {
+		if k != "id" {
+			capability[k] = v
+		}
+	}
+	return Object{"capability": capability}, nil
+}
+
+// listTemplates applies the templatefilter: featured and community list the
+// public templates, self those of the caller, and executable all of them.
+func listTemplates(s *Server, params url.Values) (Object, error) {
+	filter := params.Get("templatefilter")
+	return s.list("template", params, func(template Object) bool {
+		switch filter {
+		case "featured":
+			return template["isfeatured"] == true
+		case "community":
+			return template["ispublic"] == true && template["isfeatured"] != true
+		case "self", "selfexecutable":
+			return template["account"] == owner["account"]
+		case "executable", "all":
+			return true
+		}
+		return false
+	})
+}
+
+func listHandler(kind string) HandlerFunc {
+	return func(s *Server, params url.Values) (Object, error) {
+		return s.list(kind, params, nil)
+	}
+}
+
+func deleteHandler(kind string) HandlerFunc {
+	return func(s *Server, params url.Values) (Object, error) {
+		obj, err := s.mustGet(kind, params, "id")
+		if err != nil {
+			return nil, err
+		}
+		s.remove(kind, obj["id"].(string))
+		return success(), nil
+	}
+}
+
+func success() Object {
+	return Object{"success": true}
+}
+
+// without returns a copy of params without keys, for parameters that are
+// not plain field filters.
+func without(params url.Values, keys ...string) url.Values {
+	out := url.Values{}
+	for k, vs := range params {
+		out[k] = vs
+	}
+	for _, k := range keys {
+		out.Del(k)
+	}
+	return out
+}
+
+// split returns the values of a list parameter.
+func split(params url.Values, key string) []string {
+	var values []string
+	for _, v := range params[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// setString copies the string parameters keys of params to obj.
+func setString(obj Object, params url.Values, keys ...string) {
+	for _, k := range keys {
+		if v := params.Get(k); v != "" {
+			obj[k] = v
+		}
+	}
+}
+
+// setInt copies the integer parameters keys of params to obj.
+func setInt(obj Object, params url.Values, keys ...string) error {
+	for _, k := range keys {
+		if v := params.Get(k); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Errorf("Unable to execute API command due to invalid value for %s: %s", k, v)
+			}
+			obj[k] = n
+		}
+	}
+	return nil
+}
+
+func created() string {
+	return time.Now().UTC().Format("2006-01-02T15:04:05+0000")
+}
+
+func (s *Server) zoneOf(params url.Values) (Object, error) {
+	if params.Get("zoneid") != "" {
+		return s.mustGet("zone", params, "zoneid")
+	}
+	zones := s.objects["zone"]
+	if len(zones) == 0 {
+		return nil, Errorf("No zone available")
+	}
+	return zones[0], nil
+}
+
+// nextAddress returns a free host address of cidr, skipping the first one
+// which is used by the gateway.
+func (s *Server) nextAddress(cidr string) string {
+	ip, _, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return ""
+	}
+	ip = ip.To4()
+	s.addresses[cidr]++
+	return fmt.Sprintf("%d.%d.%d.%d", ip[0], ip[1], ip[2], s.addresses[cidr]+1)
+}
+
+func listVirtualMachines(s *Server, params url.Values) (Object, error) {
+	networkID := params.Get("networkid")
+	return s.list("virtualmachine", without(params, "networkid"), func(vm Object) bool {
+		if networkID == "" {
+			return true
+		}
+		for _, nic := range vm["nic"].([]Object) {
+			if nic["networkid"] == networkID {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func deployVirtualMachine(s *Server, params url.Values) (Object, error) {
+	offering, err := s.mustGet("serviceoffering", params, "serviceofferingid")
+	if err != nil {
+		return nil, err
+	}
+	template, err := s.mustGet("template", params, "templateid")
+	if err != nil {
+		return nil, err
+	}
+	zone, err := s.mustGet("zone", params, "zoneid")
+	if err != nil {
+		return nil, err
+	}
+
+	nics := []Object{}
+	for i, id := range split(params, "networkids") {
+		network := s.get("network", id)
+		if network == nil {
+			return nil, Errorf("Unable to find network by id %s", id)
+		}
+		nics = append(nics, Object{
+			"id":          newID(),
+			"gateway":     network["gateway"],
+			"ipaddress":   s.nextAddress(network["cidr"].(string)),
+			"isdefault":   i == 0,
+			"macaddress":  fmt.Sprintf("02:00:4c:%02x:%02x:%02x", len(s.objects["virtualmachine"]), i, len(nics)),
+			"netmask":     network["netmask"],
+			"networkid":   network["id"],
+			"networkname": network["name"],
+			"traffictype": "Guest",
+			"type":        network["type"],
+		})
+	}
+
+	affinityGroups, err := s.affinityGroupsOf(params)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []Object{}
+	for _, name := range split(params, "securitygroupnames") {
+		sg := findByName(s.objects["securitygroup"], name)
+		if sg == nil {
+			return nil, Errorf("Unable to find group by name %s", name)
+		}
+		groups = append(groups, Object{"id": sg["id"], "name": sg["name"]})
+	}
+	for _, id := range split(params, "securitygroupids") {
+		sg := s.get("securitygroup", id)
+		if sg == nil {
+			return nil, Errorf("Unable to find group by id %s", id)
+		}
+		groups = append(groups, Object{"id": sg["id"], "name": sg["name"]})
+	}
+
+	if keyPair := params.Get("keypair"); keyPair != "" && s.keyPairOf(params, keyPair) == nil {
+		return nil, Errorf("A key pair with name '%s' was not found.", keyPair)
+	}
+
+	id := newID()
+	name := params.Get("name")
+	if name == "" {
+		name = "VM-" + id
+	}
+	displayName := params.Get("displayname")
+	if displayName == "" {
+		displayName = name
+	}
+
+	vm := owned(Object{
+		"id":                  id,
+		"name":                name,
+		"displayname":         displayName,
+		"zoneid":              zone["id"],
+		"zonename":            zone["name"],
+		"serviceofferingid":   offering["id"],
+		"serviceofferingname": offering["name"],
+		"templateid":          template["id"],
+		"templatename":        template["name"],
+		"templatedisplaytext": template["displaytext"],
+		"state":               "Running",
+		"hostid":              "c0a8012a-0000-4000-8000-000000000001",
+		"hostname":            "host1",
+		"hypervisor":          template["hypervisor"],
+		"created":             created(),
+		"nic":                 nics,
+		"securitygroup":       groups,
+	})
+	setString(vm, params, "keypair", "group")
+	if err := s.setOwner(vm, params); err != nil {
+		return nil, err
+	}
+	s.add("virtualmachine", vm)
+	s.setAffinityGroups(vm, affinityGroups)
+	if userData := params.Get("userdata"); userData != "" {
+		s.userData[id] = userData
+	}
+
+	return Object{"virtualmachine": vm}, nil
+}
+
+func updateVirtualMachine(s *Server, params url.Values) (Object, error) {
+	vm, err := s.mustGet("virtualmachine", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	setString(vm, params, "displayname", "group")
+	return Object{"virtualmachine": vm}, nil
+}
+
+func getVirtualMachineUserData(s *Server, params url.Values) (Object, error) {
+	vm, err := s.mustGet("virtualmachine", params, "virtualmachineid")
+	if err != nil {
+		return nil, err
+	}
+	return Object{"virtualmachineuserdata": Object{
+		"virtualmachineid": vm["id"],
+		"userdata":         s.userData[vm["id"].(string)],
+	}}, nil
+}
+
+func destroyVirtualMachine(s *Server, params url.Values) (Object, error) {
+	vm, err := s.mustGet("virtualmachine", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	for _, volume := range s.objects["volume"] {
+		if volume["virtualmachineid"] == vm["id"] {
+			delete(volume, "virtualmachineid")
+		}
+	}
+	s.remove("virtualmachine", vm["id"].(string))
+	vm["state"] = "Destroyed"
+	return Object{"virtualmachine": vm}, nil
+}
+
+func setVirtualMachineState(state string) HandlerFunc {
+	return func(s *Server, params url.Values) (Object, error) {
+		vm, err := s.mustGet("virtualmachine", params, "id")
+		if err != nil {
+			return nil, err
+		}
+		vm["state"] = state
+		return Object{"virtualmachine": vm}, nil
+	}
+}
+
+func findByName(objs []Object, name string) Object {
+	for _, obj := range objs {
+		if obj["name"] == name {
+			return obj
+		}
+	}
+	return nil
+}
+
+func createNetwork(s *Server, params url.Values) (Object, error) {
+	offering, err := s.mustGet("networkoffering", params, "networkofferingid")
+	if err != nil {
+		return nil, err
+	}
+	zone, err := s.mustGet("zone", params, "zoneid")
+	if err != nil {
+		return nil, err
+	}
+
+	gateway := params.Get("gateway")
+	if gateway == "" {
+		gateway = fmt.Sprintf("10.1.%d.1", len(s.objects["network"])+1)
+	}
+	netmask := params.Get("netmask")
+	if netmask == "" {
+		netmask = "255.255.255.0"
+	}
+	ip := net.ParseIP(gateway).To4()
+	mask := net.IPMask(net.ParseIP(netmask).To4())
+	if ip == nil || mask == nil {
+		return nil, Errorf("Invalid gateway %s or netmask %s", gateway, netmask)
+	}
+	cidr := (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+
+	network := owned(Object{
+		"name":                params.Get("name"),
+		"displaytext":         params.Get("displaytext"),
+		"networkofferingid":   offering["id"],
+		"networkofferingname": offering["name"],
+		"zoneid":              zone["id"],
+		"zonename":            zone["name"],
+		"gateway":             gateway,
+		"netmask":             netmask,
+		"cidr":                cidr,
+		"state":               "Allocated",
+		"type":                offering["guestiptype"],
+		"traffictype":         "Guest",
+		"networkdomain":       "cs.internal",
+	})
+	setString(network, params, "vlan", "networkdomain")
+	if err := s.setOwner(network, params); err != nil {
+		return nil, err
+	}
+	if err := s.setVPC(network, offering, params); err != nil {
+		return nil, err
+	}
+	s.add("network", network)
+
+	return Object{"network": network}, nil
+}
+
+func updateNetwork(s *Server, params url.Values) (Object, error) {
+	network, err := s.mustGet("network", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if params.Get("networkofferingid") != "" {
+		offering, err := s.mustGet("networkoffering", params, "networkofferingid")
+		if err != nil {
+			return nil, err
+		}
+		network["networkofferingid"] = offering["id"]
+		network["networkofferingname"] = offering["name"]
+	}
+	setString(network, params, "name", "displaytext")
+	return Object{"network": network}, nil
+}
+
+func deleteNetwork(s *Server, params url.Values) (Object, error) {
+	network, err := s.mustGet("network", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	for _, vm := range s.objects["virtualmachine"] {
+		for _, nic := range vm["nic"].([]Object) {
+			if nic["networkid"] == network["id"] {
+				return nil, &Error{ErrorCode: 530, ErrorText: "Network is in use by virtual machine " + vm["name"].(string)}
+			}
+		}
+	}
+	s.remove("network", network["id"].(string))
+	return success(), nil
+}
+
+func associateIpAddress(s *Server, params url.Values) (Object, error) {
+	s.addresses["203.0.113.0/24"]++
+	ip := owned(Object{
+		"ipaddress":   fmt.Sprintf("203.0.113.%d", s.addresses["203.0.113.0/24"]+9),
+		"issourcenat": false,
+		"isstaticnat": false,
+		"state":       "Allocated",
+	})
+
+	if params.Get("networkid") != "" {
+		network, err := s.mustGet("network", params, "networkid")
+		if err != nil {
+			return nil, err
+		}
+		ip["associatednetworkid"] = network["id"]
+		ip["zoneid"] = network["zoneid"]
+		ip["zonename"] = network["zonename"]
+	} else {
+		zone, err := s.zoneOf(params)
+		if err != nil {
+			return nil, err
+		}
+		ip["zoneid"] = zone["id"]
+		ip["zonename"] = zone["name"]
+	}
+	if err := s.setOwner(ip, params); err != nil {
+		return nil, err
+	}
+	s.add("publicipaddress", ip)
+
+	return Object{"ipaddress": ip}, nil
+}
+
+func disassociateIpAddress(s *Server, params url.Values) (Object, error) {
+	ip, err := s.mustGet("publicipaddress", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if ip["issourcenat"] == true {
+		return nil, &Error{ErrorCode: 530, ErrorText: "Can't release source nat ip address"}
+	}
+	s.remove("publicipaddress", ip["id"].(string))
+	return success(), nil
+}
+
+func enableStaticNat(s *Server, params url.Values) (Object, error) {
+	ip, err := s.mustGet("publicipaddress", params, "ipaddressid")
+	if err != nil {
+		return nil, err
+	}
+	vm, err := s.mustGet("virtualmachine", params, "virtualmachineid")
+	if err != nil {
+		return nil, err
+	}
+	ip["isstaticnat"] = true
+	ip["virtualmachineid"] = vm["id"]
+	ip["virtualmachinename"] = vm["name"]
+	return success(), nil
+}
+
+func disableStaticNat(s *Server, params url.Values) (Object, error) {
+	ip, err := s.mustGet("publicipaddress", params, "ipaddressid")
+	if err != nil {
+		return nil, err
+	}
+	ip["isstaticnat"] = false
+	delete(ip, "virtualmachineid")
+	delete(ip, "virtualmachinename")
+	return success(), nil
+}
+
+func createVolume(s *Server, params url.Values) (Object, error) {
+	zone, err := s.zoneOf(params)
+	if err != nil {
+		return nil, err
+	}
+
+	volume := owned(Object{
+		"name":     params.Get("name"),
+		"zoneid":   zone["id"],
+		"zonename": zone["name"],
+		"state":    "Allocated",
+		"type":     "DATADISK",
+	})
+	if err := setVolumeSize(s, volume, params); err != nil {
+		return nil, err
+	}
+	if err := s.setOwner(volume, params); err != nil {
+		return nil, err
+	}
+	s.add("volume", volume)
+
+	return Object{"volume": volume}, nil
+}
+
+func setVolumeSize(s *Server, volume Object, params url.Values) error {
+	if params.Get("diskofferingid") != "" {
+		offering, err := s.mustGet("diskoffering", params, "diskofferingid")
+		if err != nil {
+			return err
+		}
+		volume["diskofferingid"] = offering["id"]
+		volume["diskofferingname"] = offering["name"]
+		volume["size"] = offering["disksize"].(int) * gigabyte
+	}
+	if params.Get("size") != "" {
+		size, err := strconv.Atoi(params.Get("size"))
+		if err != nil {
+			return Errorf("Invalid size %s", params.Get("size"))
+		}
+		volume["size"] = size * gigabyte
+	}
+	if _, ok := volume["size"]; !ok {
+		return Errorf("Either disk offering or size must be specified")
+	}
+	return nil
+}
+
+func attachVolume(s *Server, params url.Values) (Object, error) {
+	volume, err := s.mustGet("volume", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	vm, err := s.mustGet("virtualmachine", params, "virtualmachineid")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := volume["virtualmachineid"]; ok {
+		return nil, &Error{ErrorCode: 530, ErrorText: "Volume is already attached"}
+	}
+	volume["virtualmachineid"] = vm["id"]
+	volume["state"] = "Ready"
+	return Object{"volume": volume}, nil
+}
+
+func detachVolume(s *Server, params url.Values) (Object, error) {
+	volume, err := s.mustGet("volume", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	delete(volume, "virtualmachineid")
+	return Object{"volume": volume}, nil
+}
+
+func resizeVolume(s *Server, params url.Values) (Object, error) {
+	volume, err := s.mustGet("volume", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if err := setVolumeSize(s, volume, params); err != nil {
+		return nil, err
+	}
+	return Object{"volume": volume}, nil
+}
+
+func deleteVolume(s *Server, params url.Values) (Object, error) {
+	volume, err := s.mustGet("volume", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := volume["virtualmachineid"]; ok {
+		return nil, &Error{ErrorCode: 530, ErrorText: "Please specify a volume that is not attached to any VM"}
+	}
+	s.remove("volume", volume["id"].(string))
+	return success(), nil
+}
+
+func createFirewallRule(s *Server, params url.Values) (Object, error) {
+	ip, err := s.mustGet("publicipaddress", params, "ipaddressid")
+	if err != nil {
+		return nil, err
+	}
+
+	cidrs := split(params, "cidrlist")
+	if len(cidrs) == 0 {
+		cidrs = []string{"0.0.0.0/0"}
+	}
+
+	rule := Object{
+		"ipaddressid": ip["id"],
+		"ipaddress":   ip["ipaddress"],
+		"protocol":    params.Get("protocol"),
+		"cidrlist":    strings.Join(cidrs, ","),
+		"state":       "Active",
+	}
+	setString(rule, params, "startport", "endport")
+	if err := setInt(rule, params, "icmptype", "icmpcode"); err != nil {
+		return nil, err
+	}
+	inherit(rule, ip)
+	s.add("firewallrule", rule)
+
+	return Object{"firewallrule": rule}, nil
+}
+
+func createPortForwardingRule(s *Server, params url.Values) (Object, error) {
+	ip, err := s.mustGet("publicipaddress", params, "ipaddressid")
+	if err != nil {
+		return nil, err
+	}
+	vm, err := s.mustGet("virtualmachine", params, "virtualmachineid")
+	if err != nil {
+		return nil, err
+	}
+
+	rule := Object{
+		"ipaddressid":      ip["id"],
+		"ipaddress":        ip["ipaddress"],
+		"protocol":         params.Get("protocol"),
+		"privateport":      params.Get("privateport"),
+		"privateendport":   params.Get("privateport"),
+		"publicport":       params.Get("publicport"),
+		"publicendport":    params.Get("publicport"),
+		"virtualmachineid": vm["id"],
+		"cidrlist":         strings.Join(split(params, "cidrlist"), ","),
+		"state":            "Active",
+	}
+	setString(rule, params, "privateendport", "publicendport")
+	inherit(rule, ip)
+	s.add("portforwardingrule", rule)
+
+	return Object{"portforwardingrule": rule}, nil
+}
+
+func createLoadBalancerRule(s *Server, params url.Values) (Object, error) {
+	rule := owned(Object{
+		"name":        params.Get("name"),
+		"algorithm":   params.Get("algorithm"),
+		"privateport": params.Get("privateport"),
+		"publicport":  params.Get("publicport"),
+		"protocol":    "tcp",
+		"state":       "Add",
+	})
+	setString(rule, params, "description", "protocol")
+
+	if params.Get("publicipid") != "" {
+		ip, err := s.mustGet("publicipaddress", params, "publicipid")
+		if err != nil {
+			return nil, err
+		}
+		rule["publicipid"] = ip["id"]
+		rule["publicip"] = ip["ipaddress"]
+		rule["zoneid"] = ip["zoneid"]
+		inherit(rule, ip)
+	} else if err := s.setOwner(rule, params); err != nil {
+		return nil, err
+	}
+	s.add("loadbalancerrule", rule)
+	s.lbMembers[rule["id"].(string)] = []string{}
+
+	return Object{"loadbalancer": rule}, nil
+}
+
+func updateLoadBalancerRule(s *Server, params url.Values) (Object, error) {
+	rule, err := s.mustGet("loadbalancerrule", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	setString(rule, params, "name", "algorithm", "description")
+	return Object{"loadbalancer": rule}, nil
+}
+
+func assignToLoadBalancerRule(s *Server, params url.Values) (Object, error) {
+	rule, err := s.mustGet("loadbalancerrule", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	id := rule["id"].(string)
+	for _, vmID := range split(params, "virtualmachineids") {
+		if s.get("virtualmachine", vmID) == nil {
+			return nil, Errorf("Unable to find virtual machine %s", vmID)
+		}
+		s.lbMembers[id] = append(s.lbMembers[id], vmID)
+	}
+	rule["state"] = "Active"
+	return success(), nil
+}
+
+func removeFromLoadBalancerRule(s *Server, params url.Values) (Object, error) {
+	rule, err := s.mustGet("loadbalancerrule", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	id := rule["id"].(string)
+	for _, vmID := range split(params, "virtualmachineids") {
+		members := s.lbMembers[id]
+		for i, member := range members {
+			if member == vmID {
+				s.lbMembers[id] = append(members[:i:i], members[i+1:]...)
+				break
+			}
+		}
+	}
+	return success(), nil
+}
+
+func listLoadBalancerRuleInstances(s *Server, params url.Values) (Object, error) {
+	rule, err := s.mustGet("loadbalancerrule", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	var vms []Object
+	for _, id := range s.lbMembers[rule["id"].(string)] {
+		if vm := s.get("virtualmachine", id); vm != nil {
+			vms = append(vms, vm)
+		}
+	}
+	if len(vms) == 0 {
+		return Object{}, nil
+	}
+	return Object{"count": len(vms), "loadbalancerruleinstance": vms}, nil
+}
+
+func createSecurityGroup(s *Server, params url.Values) (Object, error) {
+	name := params.Get("name")
+	if findByName(s.objects["securitygroup"], name) != nil {
+		return nil, &Error{ErrorCode: 530, ErrorText: "Unable to create security group, a group with name " + name + " already exists."}
+	}
+
+	sg := owned(Object{
+		"name":        name,
+		"description": params.Get("description"),
+		"ingressrule": []Object{},
+		"egressrule":  []Object{},
+	})
+	if err := s.setOwner(sg, params); err != nil {
+		return nil, err
+	}
+	s.add("securitygroup", sg)
+
+	return Object{"securitygroup": sg}, nil
+}
+
+func (s *Server) securityGroupOf(params url.Values, idKey, nameKey string) (Object, error) {
+	if params.Get(idKey) != "" {
+		return s.mustGet("securitygroup", params, idKey)
+	}
+	sg := findByName(s.objects["securitygroup"], params.Get(nameKey))
+	if sg == nil {
+		return nil, Errorf("Unable to find security group %s", params.Get(nameKey))
+	}
+	return sg, nil
+}
+
+func deleteSecurityGroup(s *Server, params url.Values) (Object, error) {
+	sg, err := s.securityGroupOf(params, "id", "name")
+	if err != nil {
+		return nil, err
+	}
+	for _, vm := range s.objects["virtualmachine"] {
+		for _, group := range vm["securitygroup"].([]Object) {
+			if group["id"] == sg["id"] {
+				return nil, &Error{ErrorCode: 530, ErrorText: "Cannot delete group when it's in use by virtual machines"}
+			}
+		}
+	}
+	s.remove("securitygroup", sg["id"].(string))
+	return success(), nil
+}
+
+func listSecurityGroups(s *Server, params url.Values) (Object, error) {
+	name := params.Get("securitygroupname")
+	vmID := params.Get("virtualmachineid")
+	return s.list("securitygroup", without(params, "securitygroupname", "virtualmachineid"), func(sg Object) bool {
+		if name != "" && sg["name"] != name {
+			return false
+		}
+		if vmID == "" {
+			return true
+		}
+		vm := s.get("virtualmachine", vmID)
+		if vm == nil {
+			return false
+		}
+		for _, group := range vm["securitygroup"].([]Object) {
+			if group["id"] == sg["id"] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func authorizeSecurityGroupRule(kind string) HandlerFunc {
+	return func(s *Server, params url.Values) (Object, error) {
+		sg, err := s.securityGroupOf(params, "securitygroupid", "securitygroupname")
+		if err != nil {
+			return nil, err
+		}
+
+		cidrs := split(params, "cidrlist")
+		if len(cidrs) == 0 {
+			cidrs = []string{"0.0.0.0/0"}
+		}
+
+		rules := sg[kind].([]Object)
+		for _, cidr := range cidrs {
+			rule := Object{
+				"ruleid":   newID(),
+				"protocol": params.Get("protocol"),
+				"cidr":     cidr,
+			}
+			if err := setInt(rule, params, "startport", "endport", "icmptype", "icmpcode"); err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+		sg[kind] = rules
+
+		return Object{"securitygroup": sg}, nil
+	}
+}
+
+func revokeSecurityGroupRule(kind string) HandlerFunc {
+	return func(s *Server, params url.Values) (Object, error) {
+		id := params.Get("id")
+		for _, sg := range s.objects["securitygroup"] {
+			rules := sg[kind].([]Object)
+			for i, rule := range rules {
+				if rule["ruleid"] == id {
+					sg[kind] = append(rules[:i:i], rules[i+1:]...)
+					return success(), nil
+				}
+			}
+		}
+		return nil, Errorf("Unable to find security group rule %s", id)
+	}
+}
+
+// keyPairOf returns the key pair name of the owner in params.
+func (s *Server) keyPairOf(params url.Values, name string) Object {
+	for _, keyPair := range s.objects["sshkeypair"] {
+		if keyPair["name"] == name && matchOwner(keyPair, params) {
+			return keyPair
+		}
+	}
+	return nil
+}
+
+func (s *Server) addKeyPair(params url.Values, fingerprint string) (Object, error) {
+	name := params.Get("name")
+	if s.keyPairOf(params, name) != nil {
+		return nil, Errorf("A key pair with name '%s' already exists.", name)
+	}
+
+	keyPair := Object{
+		"name":        name,
+		"fingerprint": fingerprint,
+	}
+	for k, v := range owner {
+		keyPair[k] = v
+	}
+	if err := s.setOwner(keyPair, params); err != nil {
+		return nil, err
+	}
+	s.add("sshkeypair", keyPair)
+	return keyPair, nil
+}
+
+func createSSHKeyPair(s *Server, params url.Values) (Object, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		return nil, err
+	}
+	keyPair, err := s.addKeyPair(params, fingerprint(sshPublicKey(&key.PublicKey)))
+	if err != nil {
+		return nil, err
+	}
+
+	// The private key is only returned here and never stored.
+	response := Object{}
+	for k, v := range keyPair {
+		response[k] = v
+	}
+	response["privatekey"] = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	return Object{"keypair": response}, nil
+}
+
+func registerSSHKeyPair(s *Server, params url.Values) (Object, error) {
+	fields := strings.Fields(params.Get("publickey"))
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ssh-") {
+		return nil, Errorf("Public key is invalid")
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return nil, Errorf("Public key is invalid")
+	}
+
+	keyPair, err := s.addKeyPair(params, fingerprint(blob))
+	if err != nil {
+		return nil, err
+	}
+	return Object{"keypair": keyPair}, nil
+}
+
+func deleteSSHKeyPair(s *Server, params url.Values) (Object, error) {
+	keyPair := s.keyPairOf(params, params.Get("name"))
+	if keyPair == nil {
+		return nil, Errorf("A key pair with name '%s' does not exist for account %s in specified domain id",
+			params.Get("name"), owner["account"])
+	}
+	s.remove("sshkeypair", keyPair["id"].(string))
+	return success(), nil
+}
+
+// sshPublicKey encodes key in the wire format of OpenSSH public keys.
+func sshPublicKey(key *rsa.PublicKey) []byte {
+	var blob []byte
+	for _, field := range [][]byte{
+		[]byte("ssh-rsa"),
+		mpint(big.NewInt(int64(key.E))),
+		mpint(key.N),
+	} {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(field)))
+		blob = append(append(blob, length[:]...), field...)
+	}
+	return blob
+}
+
+func mpint(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+// fingerprint is the MD5 fingerprint of a public key, as CloudStack
+// reports it.
+func fingerprint(blob []byte) string {
+	sum := md5.Sum(blob)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":")
+}
+
+func createAffinityGroup(s *Server, params url.Values) (Object, error) {
+	name := params.Get("name")
+	typ := params.Get("type")
+
+	valid := false
+	for _, t := range s.objects["affinitygrouptype"] {
+		valid = valid || t["type"] == typ
+	}
+	if !valid {
+		return nil, Errorf("Unable to create affinity group, invalid affinity group type %s", typ)
+	}
+	for _, group := range s.objects["affinitygroup"] {
+		if group["name"] == name && matchOwner(group, params) {
+			return nil, Errorf("Unable to create affinity group, a group with name %s already exists.", name)
+		}
+	}
+
+	group := Object{
+		"name":              name,
+		"type":              typ,
+		"description":       params.Get("description"),
+		"virtualmachineids": []string{},
+	}
+	for k, v := range owner {
+		group[k] = v
+	}
+	if err := s.setOwner(group, params); err != nil {
+		return nil, err
+	}
+	s.add("affinitygroup", group)
+
+	return Object{"affinitygroup": group}, nil
+}
+
+func deleteAffinityGroup(s *Server, params url.Values) (Object, error) {
+	var group Object
+	if params.Get("id") != "" {
+		var err error
+		if group, err = s.mustGet("affinitygroup", params, "id"); err != nil {
+			return nil, err
+		}
+	} else {
+		group = findByName(s.objects["affinitygroup"], params.Get("name"))
+		if group == nil {
+			return nil, Errorf("Unable to find affinity group %s", params.Get("name"))
+		}
+	}
+
+	// Like CloudStack, deleting a group removes its virtual machines from it.
+	for _, vm := range s.objects["virtualmachine"] {
+		var groups []Object
+		refs, _ := vm["affinitygroup"].([]Object)
+		for _, g := range refs {
+			if g["id"] != group["id"] {
+				groups = append(groups, s.get("affinitygroup", g["id"].(string)))
+			}
+		}
+		s.setAffinityGroups(vm, groups)
+	}
+	s.remove("affinitygroup", group["id"].(string))
+	return success(), nil
+}
+
+func updateVMAffinityGroup(s *Server, params url.Values) (Object, error) {
+	vm, err := s.mustGet("virtualmachine", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if vm["state"] != "Stopped" {
+		return nil, Errorf("Unable to update affinity groups of the virtual machine %s, it must be in Stopped state", vm["name"])
+	}
+	groups, err := s.affinityGroupsOf(params)
+	if err != nil {
+		return nil, err
+	}
+	s.setAffinityGroups(vm, groups)
+	return Object{"virtualmachine": vm}, nil
+}
+
+// affinityGroupsOf returns the groups given by the affinitygroupids or
+// affinitygroupnames parameters.
+func (s *Server) affinityGroupsOf(params url.Values) ([]Object, error) {
+	ids, names := split(params, "affinitygroupids"), split(params, "affinitygroupnames")
+	if len(ids) > 0 && len(names) > 0 {
+		return nil, Errorf("affinitygroupids parameter is mutually exclusive with affinitygroupnames parameter")
+	}
+
+	var groups []Object
+	for _, id := range ids {
+		group := s.get("affinitygroup", id)
+		if group == nil {
+			return nil, Errorf("Unable to find affinity group by id %s", id)
+		}
+		groups = append(groups, group)
+	}
+	for _, name := range names {
+		group := findByName(s.objects["affinitygroup"], name)
+		if group == nil {
+			return nil, Errorf("Unable to find affinity group by name %s", name)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// setAffinityGroups puts vm into groups, and into no others.
+func (s *Server) setAffinityGroups(vm Object, groups []Object) {
+	for _, group := range s.objects["affinitygroup"] {
+		var ids []string
+		vmIDs, _ := group["virtualmachineids"].([]string)
+		for _, id := range vmIDs {
+			if id != vm["id"] {
+				ids = append(ids, id)
+			}
+		}
+		group["virtualmachineids"] = append([]string{}, ids...)
+	}
+
+	refs := []Object{}
+	for _, group := range groups {
+		vmIDs, _ := group["virtualmachineids"].([]string)
+		group["virtualmachineids"] = append(vmIDs, vm["id"].(string))
+		refs = append(refs, Object{"id": group["id"], "name": group["name"], "type": group["type"]})
+	}
+	vm["affinitygroup"] = refs
+}
+
+// setVPC makes network a tier of the VPC in params, with the ACL list in
+// params or default_deny.
+func (s *Server) setVPC(network, offering Object, params url.Values) error {
+	if params.Get("vpcid") == "" {
+		if offering["forvpc"] == true {
+			return Errorf("Network offering %s can be used for VPC networks only", offering["name"])
+		}
+		return nil
+	}
+	if offering["forvpc"] != true {
+		return Errorf("Network offering %s can't be used for VPC networks", offering["name"])
+	}
+
+	vpc, err := s.mustGet("vpc", params, "vpcid")
+	if err != nil {
+		return err
+	}
+	if params.Get("gateway") == "" || params.Get("netmask") == "" {
+		return Errorf("Gateway and netmask are required when creating a network in a VPC")
+	}
+	_, vpcNet, _ := net.ParseCIDR(vpc["cidr"].(string))
+	_, tierNet, _ := net.ParseCIDR(network["cidr"].(string))
+	vpcOnes, _ := vpcNet.Mask.Size()
+	tierOnes, _ := tierNet.Mask.Size()
+	if !vpcNet.Contains(tierNet.IP) || tierOnes < vpcOnes {
+		return Errorf("Network cidr %s is not within VPC cidr %s", network["cidr"], vpc["cidr"])
+	}
+
+	acl := findByName(s.objects["networkacllist"], "default_deny")
+	if params.Get("aclid") != "" {
+		if acl, err = s.aclOf(vpc, params.Get("aclid")); err != nil {
+			return err
+		}
+	}
+
+	network["vpcid"] = vpc["id"]
+	network["aclid"] = acl["id"]
+	return nil
+}
+
+// aclOf returns the ACL list id, which must be a default one or one of vpc.
+func (s *Server) aclOf(vpc Object, id string) (Object, error) {
+	acl := s.get("networkacllist", id)
+	if acl == nil {
+		return nil, Errorf("Unable to find specified ACL %s", id)
+	}
+	if vpcID, ok := acl["vpcid"]; ok && vpcID != vpc["id"] {
+		return nil, Errorf("ACL %s and network are in different VPCs", id)
+	}
+	return acl, nil
+}
+
+// replaceNetworkACLList sets the ACL list of a VPC tier or private gateway.
+func replaceNetworkACLList(s *Server, params url.Values) (Object, error) {
+	kind, key := "network", "networkid"
+	if params.Get("gatewayid") != "" {
+		kind, key = "privategateway", "gatewayid"
+	}
+	obj, err := s.mustGet(kind, params, key)
+	if err != nil {
+		return nil, err
+	}
+	vpc := s.get("vpc", fmt.Sprint(obj["vpcid"]))
+	if vpc == nil {
+		return nil, Errorf("Network %s is not in a VPC", obj["id"])
+	}
+	acl, err := s.aclOf(vpc, params.Get("aclid"))
+	if err != nil {
+		return nil, err
+	}
+	obj["aclid"] = acl["id"]
+	return success(), nil
+}
+
+func createVPC(s *Server, params url.Values) (Object, error) {
+	offering, err := s.mustGet("vpcoffering", params, "vpcofferingid")
+	if err != nil {
+		return nil, err
+	}
+	zone, err := s.mustGet("zone", params, "zoneid")
+	if err != nil {
+		return nil, err
+	}
+	ip, cidr, err := net.ParseCIDR(params.Get("cidr"))
+	if err != nil || !ip.Equal(cidr.IP) {
+		return nil, Errorf("Invalid cidr %s", params.Get("cidr"))
+	}
+
+	vpc := owned(Object{
+		"name":               params.Get("name"),
+		"displaytext":        params.Get("displaytext"),
+		"cidr":               cidr.String(),
+		"zoneid":             zone["id"],
+		"zonename":           zone["name"],
+		"vpcofferingid":      offering["id"],
+		"vpcofferingname":    offering["name"],
+		"state":              "Enabled",
+		"networkdomain":      "cs.internal",
+		"restartrequired":    false,
+		"redundantvpcrouter": offering["redundantrouter"],
+	})
+	setString(vpc, params, "networkdomain")
+	if err := s.setOwner(vpc, params); err != nil {
+		return nil, err
+	}
+	s.add("vpc", vpc)
+
+	s.addresses["203.0.113.0/24"]++
+	sourceNat := owned(Object{
+		"ipaddress":   fmt.Sprintf("203.0.113.%d", s.addresses["203.0.113.0/24"]+9),
+		"issourcenat": true,
+		"isstaticnat": false,
+		"state":       "Allocated",
+		"vpcid":       vpc["id"],
+		"zoneid":      zone["id"],
+		"zonename":    zone["name"],
+	})
+	s.add("publicipaddress", inherit(sourceNat, vpc))
+
+	return Object{"vpc": vpc}, nil
+}
+
+func updateVPC(s *Server, params url.Values) (Object, error) {
+	vpc, err := s.mustGet("vpc", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	setString(vpc, params, "name", "displaytext")
+	return Object{"vpc": vpc}, nil
+}
+
+func restartVPC(s *Server, params url.Values) (Object, error) {
+	vpc, err := s.mustGet("vpc", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if params.Get("makeredundant") == "true" {
+		vpc["redundantvpcrouter"] = true
+	}
+	vpc["restartrequired"] = false
+	return success(), nil
+}
+
+func deleteVPC(s *Server, params url.Values) (Object, error) {
+	vpc, err := s.mustGet("vpc", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	for _, network := range s.objects["network"] {
+		if network["vpcid"] == vpc["id"] {
+			return nil, Errorf("Unable to delete VPC %s, it has network %s", vpc["name"], network["name"])
+		}
+	}
+	for _, ip := range s.objects["publicipaddress"] {
+		if ip["vpcid"] == vpc["id"] {
+			s.remove("publicipaddress", ip["id"].(string))
+		}
+	}
+	s.remove("vpc", vpc["id"].(string))
+	return success(), nil
+}
+
+func createNetworkACLList(s *Server, params url.Values) (Object, error) {
+	vpc, err := s.mustGet("vpc", params, "vpcid")
+	if err != nil {
+		return nil, err
+	}
+	list := Object{
+		"name":        params.Get("name"),
+		"description": params.Get("description"),
+		"vpcid":       vpc["id"],
+	}
+	s.add("networkacllist", inherit(list, vpc))
+
+	return Object{"networkacllist": list}, nil
+}
+
+func deleteNetworkACLList(s *Server, params url.Values) (Object, error) {
+	list, err := s.mustGet("networkacllist", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := list["vpcid"]; !ok {
+		return nil, Errorf("Default Network ACL cannot be removed")
+	}
+	for _, network := range s.objects["network"] {
+		if network["aclid"] == list["id"] {
+			return nil, Errorf("ACL %s is still associated with network %s", list["name"], network["name"])
+		}
+	}
+	for _, gateway := range s.objects["privategateway"] {
+		if gateway["aclid"] == list["id"] {
+			return nil, Errorf("ACL %s is still associated with private gateway %s", list["name"], gateway["ipaddress"])
+		}
+	}
+	for _, item := range s.objects["networkacl"] {
+		if item["aclid"] == list["id"] {
+			s.remove("networkacl", item["id"].(string))
+		}
+	}
+	s.remove("networkacllist", list["id"].(string))
+	return success(), nil
+}
+
+func createNetworkACL(s *Server, params url.Values) (Object, error) {
+	if params.Get("aclid") == "" && params.Get("networkid") != "" {
+		network, err := s.mustGet("network", params, "networkid")
+		if err != nil {
+			return nil, err
+		}
+		params.Set("aclid", fmt.Sprint(network["aclid"]))
+	}
+	list, err := s.mustGet("networkacllist", params, "aclid")
+	if err != nil {
+		return nil, err
+	}
+
+	item := Object{
+		"aclid":       list["id"],
+		"action":      "Allow",
+		"traffictype": "Ingress",
+		"cidrlist":    "0.0.0.0/0",
+		"state":       "Active",
+	}
+	if params.Get("number") == "" {
+		number := 1
+		for _, other := range s.objects["networkacl"] {
+			if n, _ := other["number"].(int); other["aclid"] == list["id"] && n >= number {
+				number = n + 1
+			}
+		}
+		item["number"] = number
+	}
+	if err := s.setNetworkACLItem(list, item, params); err != nil {
+		return nil, err
+	}
+	s.add("networkacl", inherit(item, list))
+
+	return Object{"networkacl": item}, nil
+}
+
+func updateNetworkACLItem(s *Server, params url.Values) (Object, error) {
+	item, err := s.mustGet("networkacl", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	list := s.get("networkacllist", fmt.Sprint(item["aclid"]))
+
+	// Validate on a copy, so that an invalid update changes nothing.
+	updated := Object{}
+	for k, v := range item {
+		updated[k] = v
+	}
+	if err := s.setNetworkACLItem(list, updated, params); err != nil {
+		return nil, err
+	}
+	for k := range item {
+		delete(item, k)
+	}
+	for k, v := range updated {
+		item[k] = v
+	}
+
+	return Object{"networkacl": item}, nil
+}
+
+func deleteNetworkACL(s *Server, params url.Values) (Object, error) {
+	item, err := s.mustGet("networkacl", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	s.remove("networkacl", item["id"].(string))
+	return success(), nil
+}
+
+// setNetworkACLItem validates the fields of the ACL item in params and sets
+// them on item of list.
+func (s *Server) setNetworkACLItem(list, item Object, params url.Values) error {
+	if _, ok := list["vpcid"]; !ok {
+		return Errorf("Default ACL cannot be modified")
+	}
+
+	if v := params.Get("protocol"); v != "" {
+		protocol := strings.ToLower(v)
+		switch protocol {
+		case "tcp", "udp", "icmp", "all":
+		default:
+			if n, err := strconv.Atoi(protocol); err != nil || n < 0 || n > 255 {
+				return Errorf("Invalid protocol %s", v)
+			}
+		}
+		item["protocol"] = protocol
+	}
+	if v := params.Get("action"); v != "" {
+		switch strings.ToLower(v) {
+		case "allow":
+			item["action"] = "Allow"
+		case "deny":
+			item["action"] = "Deny"
+		default:
+			return Errorf("Invalid action %s, it should be allow or deny", v)
+		}
+	}
+	if v := params.Get("traffictype"); v != "" {
+		switch strings.ToLower(v) {
+		case "ingress":
+			item["traffictype"] = "Ingress"
+		case "egress":
+			item["traffictype"] = "Egress"
+		default:
+			return Errorf("Invalid traffic type %s, it should be ingress or egress", v)
+		}
+	}
+	if cidrs := split(params, "cidrlist"); len(cidrs) > 0 {
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return Errorf("Invalid cidr %s", cidr)
+			}
+		}
+		item["cidrlist"] = strings.Join(cidrs, ",")
+	}
+	setString(item, params, "startport", "endport")
+	if err := setInt(item, params, "number", "icmptype", "icmpcode"); err != nil {
+		return err
+	}
+
+	if item["protocol"] == "icmp" {
+		delete(item, "startport")
+		delete(item, "endport")
+		for _, k := range []string{"icmptype", "icmpcode"} {
+			if _, ok := item[k]; !ok {
+				item[k] = -1
+			}
+		}
+	} else {
+		delete(item, "icmptype")
+		delete(item, "icmpcode")
+	}
+
+	for _, other := range s.objects["networkacl"] {
+		if other["aclid"] == list["id"] && other["id"] != item["id"] && other["number"] == item["number"] {
+			return Errorf("ACL item with number %v already exists in ACL %s", item["number"], list["name"])
+		}
+	}
+	return nil
+}
+
+func createPrivateGateway(s *Server, params url.Values) (Object, error) {
+	vpc, err := s.mustGet("vpc", params, "vpcid")
+	if err != nil {
+		return nil, err
+	}
+	if params.Get("vlan") == "" {
+		return nil, Errorf("Unable to execute API command createprivategateway due to missing parameter vlan")
+	}
+
+	ip := net.ParseIP(params.Get("ipaddress"))
+	gateway := net.ParseIP(params.Get("gateway"))
+	mask := net.IPMask(net.ParseIP(params.Get("netmask")).To4())
+	if ip == nil || gateway == nil || len(mask) != net.IPv4len {
+		return nil, Errorf("Invalid ip address, gateway or netmask")
+	}
+	if !ip.Mask(mask).Equal(gateway.Mask(mask)) {
+		return nil, Errorf("The gateway %s and ip address %s are not in the same subnet", gateway, ip)
+	}
+	for _, other := range s.objects["privategateway"] {
+		if other["vpcid"] == vpc["id"] && other["ipaddress"] == ip.String() {
+			return nil, Errorf("Private gateway with ip address %s already exists in VPC %s", ip, vpc["name"])
+		}
+	}
+
+	var physicalNetwork Object
+	if params.Get("physicalnetworkid") != "" {
+		if physicalNetwork, err = s.mustGet("physicalnetwork", params, "physicalnetworkid"); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, obj := range s.objects["physicalnetwork"] {
+			if obj["zoneid"] == vpc["zoneid"] {
+				physicalNetwork = obj
+				break
+			}
+		}
+	}
+	if physicalNetwork == nil {
+		return nil, Errorf("Unable to find a physical network in zone %s", vpc["zonename"])
+	}
+
+	acl := findByName(s.objects["networkacllist"], "default_deny")
+	if params.Get("aclid") != "" {
+		if acl, err = s.aclOf(vpc, params.Get("aclid")); err != nil {
+			return nil, err
+		}
+	}
+
+	privateGateway := Object{
+		"ipaddress":          ip.String(),
+		"gateway":            gateway.String(),
+		"netmask":            params.Get("netmask"),
+		"vlan":               "vlan://" + strings.TrimPrefix(params.Get("vlan"), "vlan://"),
+		"vpcid":              vpc["id"],
+		"physicalnetworkid":  physicalNetwork["id"],
+		"sourcenatsupported": params.Get("sourcenatsupported") == "true",
+		"aclid":              acl["id"],
+		"zoneid":             vpc["zoneid"],
+		"zonename":           vpc["zonename"],
+		"state":              "Ready",
+	}
+	s.add("privategateway", inherit(privateGateway, vpc))
+
+	return Object{"privategateway": privateGateway}, nil
+}
+
+func deletePrivateGateway(s *Server, params url.Values) (Object, error) {
+	privateGateway, err := s.mustGet("privategateway", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range s.objects["staticroute"] {
+		if route["gatewayid"] == privateGateway["id"] {
+			return nil, Errorf("Can't delete private gateway %s as it has static routes applied", privateGateway["id"])
+		}
+	}
+	s.remove("privategateway", privateGateway["id"].(string))
+	return success(), nil
+}
+
+func createStaticRoute(s *Server, params url.Values) (Object, error) {
+	privateGateway, err := s.mustGet("privategateway", params, "gatewayid")
+	if err != nil {
+		return nil, err
+	}
+	ip, cidr, err := net.ParseCIDR(params.Get("cidr"))
+	if err != nil || !ip.Equal(cidr.IP) {
+		return nil, Errorf("Invalid cidr %s", params.Get("cidr"))
+	}
+
+	vpc := s.get("vpc", fmt.Sprint(privateGateway["vpcid"]))
+	_, vpcNet, _ := net.ParseCIDR(vpc["cidr"].(string))
+	if vpcNet.Contains(cidr.IP) || cidr.Contains(vpcNet.IP) {
+		return nil, Errorf("CIDR should be outside of VPC cidr %s", vpc["cidr"])
+	}
+	for _, other := range s.objects["staticroute"] {
+		if other["vpcid"] == vpc["id"] && other["cidr"] == cidr.String() {
+			return nil, Errorf("Static route with cidr %s already exists in VPC %s", cidr, vpc["name"])
+		}
+	}
+
+	route := owned(Object{
+		"cidr":      cidr.String(),
+		"gatewayid": privateGateway["id"],
+		"vpcid":     vpc["id"],
+		"state":     "Active",
+	})
+	s.add("staticroute", inherit(route, privateGateway))
+
+	return Object{"staticroute": route}, nil
+}
+
+func deleteStaticRoute(s *Server, params url.Values) (Object, error) {
+	route, err := s.mustGet("staticroute", params, "id")
+	if err != nil {
+		return nil, err
+	}
+	s.remove("staticroute", route["id"].(string))
+	return success(), nil
+}
@@ -0,0 +1,549 @@
+// Package cstest provides an in-process CloudStack API simulator for tests.
+//
+// The simulator answers API calls from the objects it stores in memory,
+// using the JSON format of the real management server: list commands return
+// {"<command>response": {"count": n, "<kind>": [...]}}, async commands return
+// a jobid to be polled with queryAsyncJobResult, and errors are reported with
+// the CloudStack error code as the HTTP status.
+package cstest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Object is a CloudStack object as it appears in API responses.
+type Object map[string]interface{}
+
+// HandlerFunc runs a command. It is called with the server locked and
+// returns the body of the response, or of the job result for async commands.
+type HandlerFunc func(s *Server, params url.Values) (Object, error)
+
+type handler struct {
+	async bool
+	fn    HandlerFunc
+}
+
+type job struct {
+	command string
+	status  int
+	result  Object
+}
+
+// Error is an API error answered with ErrorCode as the HTTP status.
+type Error struct {
+	ErrorCode int
+	ErrorText string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.ErrorCode, e.ErrorText)
+}
+
+// Errorf returns a parameter error (431) like the management server does
+// for invalid or missing parameters.
+func Errorf(format string, args ...interface{}) error {
+	return &Error{ErrorCode: 431, ErrorText: fmt.Sprintf(format, args...)}
+}
+
+// Server is a CloudStack API simulator listening on a local address.
+type Server struct {
+	*httptest.Server
+
+	// APIKey and SecretKey, or Username and Password, are the credentials
+	// the server accepts.
+	APIKey    string
+	SecretKey string
+	Username  string
+	Password  string
+
+	mu       sync.Mutex
+	objects  map[string][]Object
+	jobs     map[string]*job
+	handlers map[string]handler
+	calls    map[string]int
+
+	// held lists the commands whose jobs stay pending until released.
+	held map[string]bool
+
+	// sessions maps the session keys given by login to their cookie.
+	sessions map[string]string
+
+	// lbMembers holds the virtual machine ids assigned to each load
+	// balancer rule and addresses the next host number of each CIDR.
+	lbMembers map[string][]string
+	addresses map[string]int
+
+	// userData holds the user data of each virtual machine, which list
+	// commands don't return.
+	userData map[string]string
+}
+
+// NewServer starts a simulator with a zone, offerings and a template, and
+// registers the commands used by the provider.
+func NewServer() *Server {
+	s := &Server{
+		APIKey:    "test-apikey",
+		SecretKey: "test-secretkey",
+		Username:  "admin",
+		Password:  "password",
+		objects:   map[string][]Object{},
+		jobs:      map[string]*job{},
+		handlers:  map[string]handler{},
+		calls:     map[string]int{},
+		held:      map[string]bool{},
+		sessions:  map[string]string{},
+		lbMembers: map[string][]string{},
+		addresses: map[string]int{},
+		userData:  map[string]string{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	registerHandlers(s)
+	seed(s)
+
+	return s
+}
+
+// EndPoint returns the API URL of the server.
+func (s *Server) EndPoint() string {
+	return s.URL + "/client/api"
+}
+
+// Handle registers fn to answer command. It replaces the handler the server
+// registered for the command, if any.
+func (s *Server) Handle(command string, async bool, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[strings.ToLower(command)] = handler{async: async, fn: fn}
+}
+
+// Calls returns how many times command was called.
+func (s *Server) Calls(command string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[strings.ToLower(command)]
+}
+
+// HoldJobs keeps the jobs started by command pending, as if they ran for a
+// long time. The command still takes effect right away.
+func (s *Server) HoldJobs(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.held[strings.ToLower(command)] = true
+}
+
+// ReleaseJobs finishes the jobs of command held by HoldJobs and lets the
+// next ones finish right away.
+func (s *Server) ReleaseJobs(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	command = strings.ToLower(command)
+	delete(s.held, command)
+	for _, j := range s.jobs {
+		if j.command == command && j.status == 0 {
+			j.status = 1
+		}
+	}
+}
+
+// ExpireSessions ends the sessions opened by login, so that calls made with
+// them fail until the client logs in again.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions = map[string]string{}
+}
+
+// SetCapability changes a field of the listCapabilities response, e.g.
+// "cloudstackversion".
+func (s *Server) SetCapability(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.get("capability", "capability")[key] = value
+}
+
+// Add stores obj as an object of kind, e.g. "virtualmachine", giving it an
+// id unless it has one. It returns obj.
+func (s *Server) Add(kind string, obj Object) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.add(kind, obj)
+}
+
+// Get returns the object of kind with id, or nil.
+func (s *Server) Get(kind, id string) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(kind, id)
+}
+
+// All returns the objects of kind.
+func (s *Server) All(kind string) []Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Object(nil), s.objects[kind]...)
+}
+
+func (s *Server) add(kind string, obj Object) Object {
+	if _, ok := obj["id"]; !ok {
+		obj["id"] = newID()
+	}
+	s.objects[kind] = append(s.objects[kind], obj)
+	return obj
+}
+
+func (s *Server) get(kind, id string) Object {
+	for _, obj := range s.objects[kind] {
+		if obj["id"] == id {
+			return obj
+		}
+	}
+	return nil
+}
+
+// mustGet returns the object of kind with the id given by params[key].
+func (s *Server) mustGet(kind string, params url.Values, key string) (Object, error) {
+	id := params.Get(key)
+	if id == "" {
+		return nil, Errorf("Unable to execute API command due to missing parameter %s", key)
+	}
+	obj := s.get(kind, id)
+	if obj == nil {
+		return nil, Errorf("Unable to execute API command due to invalid value. Invalid parameter %s value=%s due to incorrect long value format, or entity does not exist", key, id)
+	}
+	return obj, nil
+}
+
+func (s *Server) remove(kind, id string) {
+	objs := s.objects[kind]
+	for i, obj := range objs {
+		if obj["id"] == id {
+			s.objects[kind] = append(objs[:i:i], objs[i+1:]...)
+			return
+		}
+	}
+}
+
+// controlParams are not used to filter list results.
+var controlParams = map[string]bool{
+	"command":        true,
+	"response":       true,
+	"apikey":         true,
+	"signature":      true,
+	"sessionkey":     true,
+	"page":           true,
+	"pagesize":       true,
+	"listall":        true,
+	"isrecursive":    true,
+	"keyword":        true,
+	"templatefilter": true,
+	"available":      true,
+	"isofilter":      true,
+	"projectid":      true,
+}
+
+// list answers a list command for objects of kind. Every parameter naming
+// a field filters on it; an unknown id is an error like on the real server.
+func (s *Server) list(kind string, params url.Values, match func(Object) bool) (Object, error) {
+	if id := params.Get("id"); id != "" && s.get(kind, id) == nil {
+		return nil, Errorf("Unable to execute API command list%ss due to invalid value. Invalid parameter id value=%s due to incorrect long value format, or entity does not exist", kind, id)
+	}
+
+	var items []Object
+	for _, obj := range s.objects[kind] {
+		if matchOwner(obj, params) && matchParams(obj, params) && matchTags(obj, params) &&
+			matchKeyword(obj, params) && (match == nil || match(obj)) {
+			items = append(items, obj)
+		}
+	}
+
+	count := len(items)
+	if page, _ := strconv.Atoi(params.Get("page")); page > 0 {
+		size, _ := strconv.Atoi(params.Get("pagesize"))
+		if size <= 0 {
+			size = 500
+		}
+		start := (page - 1) * size
+		if start > len(items) {
+			start = len(items)
+		}
+		end := start + size
+		if end > len(items) {
+			end = len(items)
+		}
+		items = items[start:end]
+	}
+
+	if count == 0 {
+		return Object{}, nil
+	}
+	return Object{"count": count, kind: items}, nil
+}
+
+// matchParams reports whether obj has the values of params. Like CloudStack
+// it ignores empty parameters, so that e.g. id= lists every object.
+func matchParams(obj Object, params url.Values) bool {
+	for k := range params {
+		name := strings.ToLower(k)
+		if controlParams[name] || strings.HasPrefix(name, "tags[") || params.Get(k) == "" {
+			continue
+		}
+		v, ok := obj[name]
+		if !ok {
+			return false
+		}
+		if !strings.EqualFold(fmt.Sprint(v), params.Get(k)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchOwner applies the visibility rules of list commands to objects owned
+// by an account: objects of a project are listed only with its projectid, or
+// -1 for every project, and those of other accounts only with listall or
+// their account.
+func matchOwner(obj Object, params url.Values) bool {
+	_, hasDomain := obj["domainid"]
+	_, hasAccount := obj["account"]
+	_, hasProject := obj["projectid"]
+	if !hasDomain || !hasAccount && !hasProject {
+		return true
+	}
+
+	projectID := params.Get("projectid")
+	if id, ok := obj["projectid"]; ok {
+		return projectID == "-1" || projectID == id
+	}
+	if projectID != "" {
+		return false
+	}
+
+	if obj["account"] == owner["account"] || params.Get("account") != "" {
+		return true
+	}
+	return params.Get("listall") == "true"
+}
+
+func matchKeyword(obj Object, params url.Values) bool {
+	keyword := params.Get("keyword")
+	if keyword == "" {
+		return true
+	}
+	return strings.Contains(fmt.Sprint(obj["name"]), keyword)
+}
+
+func matchTags(obj Object, params url.Values) bool {
+	for i := 0; ; i++ {
+		key := params.Get(fmt.Sprintf("tags[%d].key", i))
+		if key == "" {
+			return true
+		}
+		value := params.Get(fmt.Sprintf("tags[%d].value", i))
+
+		found := false
+		tags, _ := obj["tags"].([]Object)
+		for _, tag := range tags {
+			if tag["key"] == key && tag["value"] == value {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	command := strings.ToLower(params.Get("command"))
+	responseName := command + "response"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[command]++
+
+	if command == "login" {
+		s.login(w, params)
+		return
+	}
+
+	if err := s.authenticate(r, params); err != nil {
+		writeError(w, responseName, err)
+		return
+	}
+
+	if command == "queryasyncjobresult" {
+		s.queryAsyncJobResult(w, params)
+		return
+	}
+
+	h, ok := s.handlers[command]
+	if !ok {
+		writeError(w, responseName, &Error{ErrorCode: 432, ErrorText: "The given command does not exist or it is not available for user"})
+		return
+	}
+
+	result, err := h.fn(s, params)
+	if !h.async {
+		if err != nil {
+			writeError(w, responseName, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, Object{responseName: result})
+		return
+	}
+
+	jobID := newID()
+	response := Object{"jobid": jobID}
+	if err != nil {
+		s.jobs[jobID] = &job{command: command, status: 2, result: errorObject(err)}
+	} else {
+		s.jobs[jobID] = &job{command: command, status: 1, result: result}
+		if s.held[command] {
+			s.jobs[jobID].status = 0
+		}
+		for _, v := range result {
+			if obj, ok := v.(Object); ok {
+				response["id"] = obj["id"]
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, Object{responseName: response})
+}
+
+// authenticate accepts requests signed with the server's API key, and
+// requests carrying the session key and cookie of a session.
+func (s *Server) authenticate(r *http.Request, params url.Values) error {
+	if params.Get("apikey") == s.APIKey && s.verifySignature(params) {
+		return nil
+	}
+	if cookie, ok := s.sessions[params.Get("sessionkey")]; ok {
+		if c, err := r.Cookie("JSESSIONID"); err == nil && c.Value == cookie {
+			return nil
+		}
+	}
+	return &Error{ErrorCode: 401, ErrorText: "unable to verify user credentials and/or request signature"}
+}
+
+// verifySignature checks the signature of params like the management
+// server: the HMAC-SHA1 with the secret key of the other parameters, sorted
+// by name, URL encoded and lower cased.
+func (s *Server) verifySignature(params url.Values) bool {
+	signature, err := base64.StdEncoding.DecodeString(params.Get("signature"))
+	if err != nil {
+		return false
+	}
+
+	values := url.Values{}
+	for k, vs := range params {
+		if k != "signature" {
+			values[k] = vs
+		}
+	}
+	query := strings.Replace(strings.ToLower(values.Encode()), "+", "%20", -1)
+
+	mac := hmac.New(sha1.New, []byte(s.SecretKey))
+	mac.Write([]byte(query))
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// login opens a session for the server's user. Like the management server
+// it answers a session key and sets the JSESSIONID cookie, both of which
+// must be sent with the following calls.
+func (s *Server) login(w http.ResponseWriter, params url.Values) {
+	domain := params.Get("domain")
+	if params.Get("username") != s.Username || params.Get("password") != s.Password ||
+		(domain != "" && domain != "/" && domain != owner["domain"]) {
+		writeError(w, "loginresponse", &Error{ErrorCode: 531, ErrorText: "Failed to authenticate user " + params.Get("username") + " in domain " + domain + "; please provide valid credentials"})
+		return
+	}
+
+	sessionKey, cookie := newID(), newID()
+	s.sessions[sessionKey] = cookie
+
+	http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: cookie, Path: "/client", HttpOnly: true})
+	writeJSON(w, http.StatusOK, Object{
+		"loginresponse": Object{
+			"timeout":    "1800",
+			"sessionkey": sessionKey,
+			"username":   s.Username,
+			"account":    owner["account"],
+			"domainid":   owner["domainid"],
+			"type":       "1",
+		},
+	})
+}
+
+func (s *Server) queryAsyncJobResult(w http.ResponseWriter, params url.Values) {
+	id := params.Get("jobid")
+	j, ok := s.jobs[id]
+	if !ok {
+		writeError(w, "queryasyncjobresultresponse", Errorf("Unable to find a job with id %s", id))
+		return
+	}
+
+	response := Object{
+		"jobid":         id,
+		"jobstatus":     j.status,
+		"jobresultcode": map[bool]int{true: 0, false: 530}[j.status != 2],
+	}
+	if j.status != 0 {
+		response["jobresult"] = j.result
+	}
+	writeJSON(w, http.StatusOK, Object{"queryasyncjobresultresponse": response})
+}
+
+func errorObject(err error) Object {
+	e, ok := err.(*Error)
+	if !ok {
+		e = &Error{ErrorCode: 530, ErrorText: err.Error()}
+	}
+	return Object{
+		"errorcode":   e.ErrorCode,
+		"cserrorcode": 4350,
+		"errortext":   e.ErrorText,
+	}
+}
+
+func writeError(w http.ResponseWriter, responseName string, err error) {
+	obj := errorObject(err)
+	writeJSON(w, obj["errorcode"].(int), Object{responseName: obj})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,48 @@
+package cloudstack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+// checkSingleResult returns an error unless the search of a data source of
+// kind found exactly one of n objects.
+func checkSingleResult(kind string, n int) error {
+	switch {
+	case n == 0:
+		return fmt.Errorf("No %s matches the search, change the search criteria", kind)
+	case n > 1:
+		return fmt.Errorf("%d %s match the search, narrow it down", n, plural(kind))
+	}
+	return nil
+}
+
+func plural(noun string) string {
+	if strings.HasSuffix(noun, "s") {
+		return noun + "es"
+	}
+	return noun + "s"
+}
+
+// intValue returns n as an int, 0 when it is unset.
+func intValue(n cloudstack.NullNumber) int {
+	v, _ := n.Int64()
+	return int(v)
+}
+
+// hasTags reports whether the comma separated tags of an offering include
+// every one of want.
+func hasTags(tags, want string) bool {
+	have := map[string]bool{}
+	for _, tag := range strings.Split(tags, ",") {
+		have[strings.TrimSpace(tag)] = true
+	}
+	for _, tag := range strings.Split(want, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !have[tag] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceDiskOffering() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDiskOfferingRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			// disk_size is in GB, 0 for customized offerings.
+			"disk_size": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"min_disk_size": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"storage_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"tags": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"is_customized": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			// smallest picks the offering with the smallest disk. Customized
+			// offerings come last, having no size.
+			"smallest": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"display_text": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceDiskOfferingRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListDiskOfferingsParameter()
+	if v, ok := d.GetOk("name"); ok {
+		param.Name.Set(v)
+	}
+
+	offerings, err := config.client.ListDiskOfferings(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list disk offerings: %s", err)
+	}
+
+	offerings = filter(offerings, func(x interface{}) bool {
+		o := x.(*cloudstack.DiskOffering)
+		if v, ok := d.GetOk("disk_size"); ok && intValue(o.DiskSize) != v.(int) {
+			return false
+		}
+		if v, ok := d.GetOk("min_disk_size"); ok && intValue(o.DiskSize) < v.(int) {
+			return false
+		}
+		if v, ok := d.GetOk("storage_type"); ok && o.StorageType.String() != v.(string) {
+			return false
+		}
+		if v, ok := d.GetOk("tags"); ok && !hasTags(o.Tags.String(), v.(string)) {
+			return false
+		}
+		if v, ok := d.GetOkExists("is_customized"); ok && o.IsCustomized.Bool() != v.(bool) {
+			return false
+		}
+		return true
+	}).([]*cloudstack.DiskOffering)
+
+	if d.Get("smallest").(bool) && len(offerings) > 1 {
+		smallest := offerings[0]
+		for _, o := range offerings[1:] {
+			if diskOfferingLess(o, smallest) {
+				smallest = o
+			}
+		}
+		offerings = []*cloudstack.DiskOffering{smallest}
+	}
+	if err := checkSingleResult("disk offering", len(offerings)); err != nil {
+		return err
+	}
+	offering := offerings[0]
+
+	d.SetId(offering.Id.String())
+	d.Set("name", offering.Name.String())
+	d.Set("display_text", offering.DisplayText.String())
+	d.Set("disk_size", intValue(offering.DiskSize))
+	d.Set("storage_type", offering.StorageType.String())
+	d.Set("tags", offering.Tags.String())
+	d.Set("is_customized", offering.IsCustomized.Bool())
+
+	return nil
+}
+
+func diskOfferingLess(a, b *cloudstack.DiskOffering) bool {
+	if a.IsCustomized.Bool() != b.IsCustomized.Bool() {
+		return !a.IsCustomized.Bool()
+	}
+	return intValue(a.DiskSize) < intValue(b.DiskSize)
+}
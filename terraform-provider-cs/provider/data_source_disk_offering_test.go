@@ -0,0 +1,67 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceDiskOffering(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	server.Add("diskoffering", cstest.Object{
+		"name":        "large-ssd",
+		"displaytext": "100 GB SSD",
+		"disksize":    100,
+		"storagetype": "shared",
+		"tags":        "ssd,fast",
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_disk_offering" "foo" {
+  smallest = true
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "name", "small"),
+					resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "disk_size", "5"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_disk_offering" "foo" {
+  tags = "ssd"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "name", "large-ssd"),
+					resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "display_text", "100 GB SSD"),
+					resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "tags", "ssd,fast"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_disk_offering" "foo" {
+  is_customized = true
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_disk_offering.foo", "name", "custom"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_disk_offering" "foo" {
+  min_disk_size = 200
+}
+`),
+				ExpectError: regexp.MustCompile("No disk offering matches the search"),
+			},
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func dataSourceNetwork() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceNetworkRead,
+
+		Schema: withScopeFilters(map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"zone_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"zone_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"traffic_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"type": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.StringInSlice([]string{"Shared", "Isolated", "L2"}, true),
+			},
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"display_text": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"cidr": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"gateway": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"netmask": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"vlan": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"network_offering_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"network_offering_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"network_domain": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func dataSourceNetworkRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListNetworksParameter()
+	getScope(d, meta).setListParam(param)
+
+	_, hasZoneId := d.GetOk("zone_id")
+	_, hasZoneName := d.GetOk("zone_name")
+	if hasZoneId || hasZoneName {
+		zoneId, err := getResourceId(d, meta, "zone")
+		if err != nil {
+			return err
+		}
+		param.ZoneId.Set(zoneId)
+	}
+	if _, ok := d.GetOk("vpc_id"); ok {
+		vpcId, err := getResourceId(d, meta, "vpc")
+		if err != nil {
+			return err
+		}
+		param.VpcId.Set(vpcId)
+	}
+	if v, ok := d.GetOk("traffic_type"); ok {
+		param.TrafficType.Set(v)
+	}
+	if v, ok := d.GetOk("type"); ok {
+		param.Type.Set(v)
+	}
+	if v, ok := d.GetOk("tags"); ok {
+		param.Tags = toStringMap(v.(map[string]interface{}))
+	}
+	name, hasName := d.GetOk("name")
+	if hasName {
+		param.Keyword.Set(name)
+	}
+
+	networks, err := config.client.ListNetworks(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list networks: %s", err)
+	}
+
+	if hasName {
+		networks = filter(networks, func(network interface{}) bool {
+			return equalName(network, name.(string))
+		}).([]*cloudstack.Network)
+	}
+
+	if err := checkSingleResult("network", len(networks)); err != nil {
+		return err
+	}
+	network := networks[0]
+
+	d.SetId(network.Id.String())
+	readScope(d, network)
+	d.Set("name", network.Name.String())
+	d.Set("display_text", network.DisplayText.String())
+	d.Set("zone_id", network.ZoneId.String())
+	d.Set("zone_name", network.ZoneName.String())
+	d.Set("vpc_id", network.VpcId.String())
+	d.Set("traffic_type", network.TrafficType.String())
+	d.Set("type", network.Type.String())
+	d.Set("cidr", network.Cidr.String())
+	d.Set("gateway", network.Gateway.String())
+	d.Set("netmask", network.Netmask.String())
+	d.Set("vlan", network.Vlan.String())
+	d.Set("network_offering_id", network.NetworkOfferingId.String())
+	d.Set("network_offering_name", network.NetworkOfferingName.String())
+	d.Set("network_domain", network.NetworkDomain.String())
+	d.Set("state", network.State.String())
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceNetwork(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	zone := server.All("zone")[0]
+	domain := server.All("domain")[0]
+	offering := server.All("networkoffering")[0]
+	network := func(obj cstest.Object) cstest.Object {
+		obj["zoneid"] = zone["id"]
+		obj["zonename"] = zone["name"]
+		obj["networkofferingid"] = offering["id"]
+		obj["networkofferingname"] = offering["name"]
+		obj["traffictype"] = "Guest"
+		obj["state"] = "Setup"
+		return server.Add("network", obj)
+	}
+	shared := network(cstest.Object{
+		"name":    "platform-shared",
+		"type":    "Shared",
+		"cidr":    "192.168.100.0/24",
+		"gateway": "192.168.100.1",
+		"netmask": "255.255.255.0",
+		"vlan":    "100",
+		"tags":    []cstest.Object{{"key": "team", "value": "platform"}},
+	})
+	network(cstest.Object{
+		"name":     "platform",
+		"type":     "Isolated",
+		"cidr":     "10.20.0.0/24",
+		"account":  "user1",
+		"domainid": domain["id"],
+		"tags":     []cstest.Object{},
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_network" "foo" {
+  name      = "platform-shared"
+  zone_name = "zone1"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_network.foo", "id", shared["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "zone_id", zone["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "type", "Shared"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "cidr", "192.168.100.0/24"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "gateway", "192.168.100.1"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "netmask", "255.255.255.0"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "vlan", "100"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "network_offering_id", offering["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "state", "Setup"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_network" "foo" {
+  type = "Shared"
+
+  tags {
+    team = "platform"
+  }
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_network.foo", "name", "platform-shared"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_network" "foo" {
+  name = "platform"
+}
+`),
+				ExpectError: regexp.MustCompile("No network matches the search"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_network" "foo" {
+  name      = "platform"
+  account   = "user1"
+  domain_id = "`+domain["id"].(string)+`"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_network.foo", "cidr", "10.20.0.0/24"),
+					resource.TestCheckResourceAttr("data.cs_network.foo", "account", "user1"),
+				),
+			},
+		},
+	})
+}
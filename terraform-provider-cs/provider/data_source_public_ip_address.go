@@ -0,0 +1,147 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourcePublicIpAddress() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourcePublicIpAddressRead,
+
+		Schema: withScopeFilters(map[string]*schema.Schema{
+			"ip_address": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"zone_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"zone_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			// network_id is the public network the address comes from, as
+			// for cs_ip_address, and associated_network_id the guest
+			// network it is associated with.
+			"network_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"associated_network_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"is_source_nat": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"is_static_nat": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			// virtual_machine_id is the static NAT target.
+			"virtual_machine_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func dataSourcePublicIpAddressRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListPublicIpAddressesParameter()
+	getScope(d, meta).setListParam(param)
+
+	if v, ok := d.GetOk("ip_address"); ok {
+		param.IpAddress.Set(v)
+	}
+	_, hasZoneId := d.GetOk("zone_id")
+	_, hasZoneName := d.GetOk("zone_name")
+	if hasZoneId || hasZoneName {
+		zoneId, err := getResourceId(d, meta, "zone")
+		if err != nil {
+			return err
+		}
+		param.ZoneId.Set(zoneId)
+	}
+	if v, ok := d.GetOk("associated_network_id"); ok {
+		networkId, err := nameToID(config.client, "network", v.(string), lookup{scope: getScope(d, meta)})
+		if err != nil {
+			return err
+		}
+		param.AssociatedNetworkId.Set(networkId)
+	}
+	if _, ok := d.GetOk("vpc_id"); ok {
+		vpcId, err := getResourceId(d, meta, "vpc")
+		if err != nil {
+			return err
+		}
+		param.VpcId.Set(vpcId)
+	}
+	if v, ok := d.GetOkExists("is_source_nat"); ok {
+		param.IsSourceNat.Set(v)
+	}
+	if v, ok := d.GetOkExists("is_static_nat"); ok {
+		param.IsStaticNat.Set(v)
+	}
+	if v, ok := d.GetOk("tags"); ok {
+		param.Tags = toStringMap(v.(map[string]interface{}))
+	}
+
+	ipAddresses, err := config.client.ListPublicIpAddresses(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list ipaddress: %s", err)
+	}
+
+	if v, ok := d.GetOk("virtual_machine_id"); ok {
+		ipAddresses = filter(ipAddresses, func(ip interface{}) bool {
+			return ip.(*cloudstack.PublicIpAddress).VirtualMachineId.String() == v.(string)
+		}).([]*cloudstack.PublicIpAddress)
+	}
+
+	if err := checkSingleResult("public IP address", len(ipAddresses)); err != nil {
+		return err
+	}
+	ipAddress := ipAddresses[0]
+
+	d.SetId(ipAddress.Id.String())
+	readScope(d, ipAddress)
+	d.Set("ip_address", ipAddress.IpAddress.String())
+	d.Set("zone_id", ipAddress.ZoneId.String())
+	d.Set("zone_name", ipAddress.ZoneName.String())
+	d.Set("network_id", ipAddress.NetworkId.String())
+	d.Set("associated_network_id", ipAddress.AssociatedNetworkId.String())
+	d.Set("vpc_id", ipAddress.VpcId.String())
+	d.Set("is_source_nat", ipAddress.IsSourceNat.Bool())
+	d.Set("is_static_nat", ipAddress.IsStaticNat.Bool())
+	d.Set("virtual_machine_id", ipAddress.VirtualMachineId.String())
+	d.Set("state", ipAddress.State.String())
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourcePublicIpAddress(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	zone := server.All("zone")[0]
+	domain := server.All("domain")[0]
+	owned := func(obj cstest.Object) cstest.Object {
+		obj["zoneid"] = zone["id"]
+		obj["zonename"] = zone["name"]
+		obj["account"] = "admin"
+		obj["domainid"] = domain["id"]
+		obj["state"] = "Allocated"
+		if _, ok := obj["tags"]; !ok {
+			obj["tags"] = []cstest.Object{}
+		}
+		return obj
+	}
+	network := server.Add("network", owned(cstest.Object{"name": "net01", "cidr": "10.10.0.0/24"}))
+	vpc := server.Add("vpc", owned(cstest.Object{"name": "vpc01", "cidr": "10.0.0.0/16"}))
+	sourceNat := server.Add("publicipaddress", owned(cstest.Object{
+		"ipaddress":           "203.0.113.5",
+		"issourcenat":         true,
+		"isstaticnat":         false,
+		"associatednetworkid": network["id"],
+	}))
+	server.Add("publicipaddress", owned(cstest.Object{
+		"ipaddress":          "203.0.113.6",
+		"issourcenat":        false,
+		"isstaticnat":        true,
+		"virtualmachineid":   "5f1c6a2e-7b3d-4e8f-9a0b-1c2d3e4f5a6b",
+		"virtualmachinename": "web01",
+	}))
+	server.Add("publicipaddress", owned(cstest.Object{
+		"ipaddress":   "203.0.113.7",
+		"issourcenat": false,
+		"isstaticnat": false,
+		"vpcid":       vpc["id"],
+		"tags":        []cstest.Object{{"key": "role", "value": "ingress"}},
+	}))
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "firewallrule"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_public_ip_address" "foo" {
+  associated_network_id = "net01"
+  is_source_nat         = true
+}
+
+resource "cs_firewall_rule" "foo" {
+  ip_address_id = "${data.cs_public_ip_address.foo.id}"
+  protocol      = "tcp"
+  cidr_list     = ["0.0.0.0/0"]
+  start_port    = 22
+  end_port      = 22
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "id", sourceNat["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "ip_address", "203.0.113.5"),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "zone_id", zone["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "associated_network_id", network["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "is_static_nat", "false"),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "account", "admin"),
+					resource.TestCheckResourceAttrPair(
+						"cs_firewall_rule.foo", "ip_address_id", "data.cs_public_ip_address.foo", "id"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_public_ip_address" "foo" {
+  virtual_machine_id = "5f1c6a2e-7b3d-4e8f-9a0b-1c2d3e4f5a6b"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "ip_address", "203.0.113.6"),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "is_static_nat", "true"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_public_ip_address" "foo" {
+  vpc_id = "vpc01"
+
+  tags {
+    role = "ingress"
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "ip_address", "203.0.113.7"),
+					resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "vpc_id", vpc["id"].(string)),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_public_ip_address" "foo" {
+  ip_address = "203.0.113.6"
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_public_ip_address.foo", "virtual_machine_id", "5f1c6a2e-7b3d-4e8f-9a0b-1c2d3e4f5a6b"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_public_ip_address" "foo" {
+  is_source_nat = false
+}
+`),
+				ExpectError: regexp.MustCompile("2 public IP addresses match the search"),
+			},
+		},
+	})
+}
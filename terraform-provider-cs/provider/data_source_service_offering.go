@@ -0,0 +1,184 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceServiceOffering() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceServiceOfferingRead,
+
+		Schema: map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cpu_number": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			// cpu_speed is in MHz
+			"cpu_speed": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			// memory is in MB
+			"memory": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Computed: true,
+			},
+			"min_cpu_number": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_cpu_speed": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			"min_memory": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+			// storage_type is shared or local.
+			"storage_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			// tags are the comma separated storage tags, host_tags those of
+			// the hosts.
+			"tags": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"host_tags": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"is_customized": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			// smallest picks the offering with the least memory, then
+			// CPUs, then CPU speed, instead of failing when several match.
+			// Customized offerings come last, having no size.
+			"smallest": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"display_text": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"offer_ha": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"limit_cpu_use": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceServiceOfferingRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListServiceOfferingsParameter()
+	if v, ok := d.GetOk("name"); ok {
+		param.Name.Set(v)
+	}
+
+	offerings, err := config.client.ListServiceOfferings(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list service offerings: %s", err)
+	}
+
+	offerings = filter(offerings, func(x interface{}) bool {
+		o := x.(*cloudstack.ServiceOffering)
+		for _, f := range []struct {
+			key   string
+			value int
+			min   bool
+		}{
+			{"cpu_number", intValue(o.CpuNumber), false},
+			{"cpu_speed", intValue(o.CpuSpeed), false},
+			{"memory", intValue(o.Memory), false},
+			{"min_cpu_number", intValue(o.CpuNumber), true},
+			{"min_cpu_speed", intValue(o.CpuSpeed), true},
+			{"min_memory", intValue(o.Memory), true},
+		} {
+			v, ok := d.GetOk(f.key)
+			if ok && (f.min && f.value < v.(int) || !f.min && f.value != v.(int)) {
+				return false
+			}
+		}
+		if v, ok := d.GetOk("storage_type"); ok && o.StorageType.String() != v.(string) {
+			return false
+		}
+		if v, ok := d.GetOk("tags"); ok && !hasTags(o.Tags.String(), v.(string)) {
+			return false
+		}
+		if v, ok := d.GetOk("host_tags"); ok && !hasTags(o.HostTags.String(), v.(string)) {
+			return false
+		}
+		if v, ok := d.GetOkExists("is_customized"); ok && o.IsCustomized.Bool() != v.(bool) {
+			return false
+		}
+		return true
+	}).([]*cloudstack.ServiceOffering)
+
+	if d.Get("smallest").(bool) && len(offerings) > 1 {
+		smallest := offerings[0]
+		for _, o := range offerings[1:] {
+			if serviceOfferingLess(o, smallest) {
+				smallest = o
+			}
+		}
+		offerings = []*cloudstack.ServiceOffering{smallest}
+	}
+	if err := checkSingleResult("service offering", len(offerings)); err != nil {
+		return err
+	}
+	offering := offerings[0]
+
+	d.SetId(offering.Id.String())
+	d.Set("name", offering.Name.String())
+	d.Set("display_text", offering.DisplayText.String())
+	d.Set("cpu_number", intValue(offering.CpuNumber))
+	d.Set("cpu_speed", intValue(offering.CpuSpeed))
+	d.Set("memory", intValue(offering.Memory))
+	d.Set("storage_type", offering.StorageType.String())
+	d.Set("tags", offering.Tags.String())
+	d.Set("host_tags", offering.HostTags.String())
+	d.Set("is_customized", offering.IsCustomized.Bool())
+	d.Set("offer_ha", offering.OfferHa.Bool())
+	d.Set("limit_cpu_use", offering.LimitCpuUse.Bool())
+
+	return nil
+}
+
+func serviceOfferingLess(a, b *cloudstack.ServiceOffering) bool {
+	if a.IsCustomized.Bool() != b.IsCustomized.Bool() {
+		return !a.IsCustomized.Bool()
+	}
+	if intValue(a.Memory) != intValue(b.Memory) {
+		return intValue(a.Memory) < intValue(b.Memory)
+	}
+	if intValue(a.CpuNumber) != intValue(b.CpuNumber) {
+		return intValue(a.CpuNumber) < intValue(b.CpuNumber)
+	}
+	return intValue(a.CpuSpeed) < intValue(b.CpuSpeed)
+}
@@ -0,0 +1,95 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceServiceOffering(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	offering := func(name string, cpus, memory int, tags string) cstest.Object {
+		return server.Add("serviceoffering", cstest.Object{
+			"name":        name,
+			"cpunumber":   cpus,
+			"cpuspeed":    2000,
+			"memory":      memory,
+			"storagetype": "shared",
+			"tags":        tags,
+			"offerha":     true,
+		})
+	}
+	large := offering("large", 4, 8192, "ssd")
+	offering("xlarge", 8, 16384, "ssd")
+	offering("highcpu", 8, 8192, "")
+	server.Add("serviceoffering", cstest.Object{
+		"name":         "custom",
+		"storagetype":  "shared",
+		"iscustomized": true,
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_service_offering" "foo" {
+  min_cpu_number = 4
+  min_memory     = 8192
+  smallest       = true
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "id", large["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "name", "large"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "cpu_number", "4"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "cpu_speed", "2000"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "memory", "8192"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "tags", "ssd"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "offer_ha", "true"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_service_offering" "foo" {
+  cpu_number = 8
+  tags       = "ssd"
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_service_offering.foo", "name", "xlarge"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_service_offering" "foo" {
+  name = "small"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "memory", "512"),
+					resource.TestCheckResourceAttr("data.cs_service_offering.foo", "is_customized", "false"),
+				),
+			},
+			resource.TestStep{
+				// Customized offerings, which have no memory, come last.
+				Config: testAccConfig(server, `
+data "cs_service_offering" "foo" {
+  smallest = true
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_service_offering.foo", "name", "small"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_service_offering" "foo" {
+  min_cpu_number = 4
+}
+`),
+				ExpectError: regexp.MustCompile("3 service offerings match the search"),
+			},
+		},
+	})
+}
@@ -0,0 +1,229 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func dataSourceTemplate() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceTemplateRead,
+
+		Schema: map[string]*schema.Schema{
+			"template_filter": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "executable",
+				ValidateFunc: validation.StringInSlice(templateFilters, false),
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"name_regex": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.ValidateRegexp,
+			},
+			"zone_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"zone_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"hypervisor": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"os_type_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"os_type_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"tags": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"project_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// most_recent picks the newest of the ready templates found
+			// instead of failing when there are more than one.
+			"most_recent": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"display_text": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"size": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"is_ready": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"is_featured": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"is_public": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"password_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"ssh_key_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+			"checksum": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"format": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"template_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"created": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"account": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"domain": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceTemplateRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	l, err := getLookup(d, meta, "template")
+	if err != nil {
+		return err
+	}
+	param := newListTemplatesParameter(l)
+	if v, ok := d.GetOk("name"); ok {
+		param.Name.Set(v)
+	}
+	if v, ok := d.GetOk("tags"); ok {
+		param.Tags = toStringMap(v.(map[string]interface{}))
+	}
+
+	templates, err := config.client.ListTemplates(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list templates: %s", err)
+	}
+
+	var nameRegexp *regexp.Regexp
+	if v, ok := d.GetOk("name_regex"); ok {
+		nameRegexp = regexp.MustCompile(v.(string))
+	}
+	osTypeId, hasOsTypeId := d.GetOk("os_type_id")
+	osTypeName, hasOsTypeName := d.GetOk("os_type_name")
+	mostRecent := d.Get("most_recent").(bool)
+
+	// A template is listed once for every zone it is in.
+	seen := map[string]bool{}
+	templates = filter(templates, func(x interface{}) bool {
+		template := x.(*cloudstack.Template)
+		switch {
+		case seen[template.Id.String()]:
+			return false
+		case nameRegexp != nil && !nameRegexp.MatchString(template.Name.String()):
+			return false
+		case hasOsTypeId && template.OsTypeId.String() != osTypeId.(string):
+			return false
+		case hasOsTypeName && template.OsTypeName.String() != osTypeName.(string):
+			return false
+		case mostRecent && !template.IsReady.Bool():
+			return false
+		}
+		seen[template.Id.String()] = true
+		return true
+	}).([]*cloudstack.Template)
+
+	if mostRecent && len(templates) > 1 {
+		templates = []*cloudstack.Template{newestTemplate(templates)}
+	}
+	if err := checkSingleResult("template", len(templates)); err != nil {
+		return err
+	}
+	template := templates[0]
+
+	d.SetId(template.Id.String())
+	d.Set("name", template.Name.String())
+	d.Set("display_text", template.DisplayText.String())
+	d.Set("zone_id", template.ZoneId.String())
+	d.Set("zone_name", template.ZoneName.String())
+	d.Set("hypervisor", template.Hypervisor.String())
+	d.Set("os_type_id", template.OsTypeId.String())
+	d.Set("os_type_name", template.OsTypeName.String())
+	d.Set("size", intValue(template.Size))
+	d.Set("is_ready", template.IsReady.Bool())
+	d.Set("is_featured", template.IsFeatured.Bool())
+	d.Set("is_public", template.IsPublic.Bool())
+	d.Set("password_enabled", template.PasswordEnabled.Bool())
+	d.Set("ssh_key_enabled", template.SshKeyEnabled.Bool())
+	d.Set("checksum", template.Checksum.String())
+	d.Set("format", template.Format.String())
+	d.Set("template_type", template.TemplateType.String())
+	d.Set("created", template.Created.String())
+	d.Set("account", template.Account.String())
+	d.Set("domain", template.Domain.String())
+
+	return nil
+}
+
+// newestTemplate returns the template created last.
+func newestTemplate(templates []*cloudstack.Template) *cloudstack.Template {
+	newest, newestCreated := templates[0], parseCreated(templates[0].Created.String())
+	for _, template := range templates[1:] {
+		if created := parseCreated(template.Created.String()); created.After(newestCreated) {
+			newest, newestCreated = template, created
+		}
+	}
+	return newest
+}
+
+// parseCreated parses the created timestamps of CloudStack objects, like
+// 2016-05-01T10:00:00+0000. Unparsable ones are taken as the oldest.
+func parseCreated(s string) time.Time {
+	t, err := time.Parse("2006-01-02T15:04:05-0700", s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,149 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceTemplate(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	zone := server.All("zone")[0]
+	centos := server.All("template")[0]
+	golden := func(name, created string, ready bool, tags ...cstest.Object) cstest.Object {
+		return server.Add("template", cstest.Object{
+			"name":         name,
+			"zoneid":       zone["id"],
+			"zonename":     zone["name"],
+			"isready":      ready,
+			"templatetype": "USER",
+			"hypervisor":   "XenServer",
+			"ostypeid":     "5e3b2c1d-0f9e-4d8c-b7a6-958473625140",
+			"ostypename":   "Ubuntu 16.04",
+			"created":      created,
+			"account":      "admin",
+			"domain":       "ROOT",
+			"tags":         tags,
+		})
+	}
+	golden("golden-20261001", "2026-10-01T02:00:00+0000", true)
+	nightly := golden("golden-20261010", "2026-10-10T02:00:00+0000", true,
+		cstest.Object{"key": "role", "value": "web"})
+	golden("golden-20261015", "2026-10-15T02:00:00+0000", false)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_template" "foo" {
+  template_filter = "featured"
+  name            = "CentOS 7"
+  zone_name       = "zone1"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_template.foo", "id", centos["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "zone_id", zone["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "hypervisor", "KVM"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "os_type_name", "CentOS 7"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "size", "10737418240"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "password_enabled", "true"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "ssh_key_enabled", "true"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "checksum", "9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_template" "foo" {
+  template_filter = "self"
+  name_regex      = "^golden-"
+  hypervisor      = "XenServer"
+  os_type_name    = "Ubuntu 16.04"
+  most_recent     = true
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_template.foo", "id", nightly["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "name", "golden-20261010"),
+					resource.TestCheckResourceAttr("data.cs_template.foo", "is_ready", "true"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_template" "foo" {
+  tags {
+    role = "web"
+  }
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_template.foo", "name", "golden-20261010"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_template" "foo" {
+  name_regex = "^golden-"
+}
+`),
+				ExpectError: regexp.MustCompile("3 templates match the search"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_template" "foo" {
+  template_filter = "featured"
+  name_regex      = "^golden-"
+}
+`),
+				ExpectError: regexp.MustCompile("No template matches the search"),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceTemplate_virtualMachine(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "virtualmachine"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  name = "zone1"
+}
+
+data "cs_template" "foo" {
+  name    = "CentOS 7"
+  zone_id = "${data.cs_zone.foo.id}"
+}
+
+resource "cs_network" "foo" {
+  name                  = "net01"
+  display_text          = "test network"
+  zone_id               = "${data.cs_zone.foo.id}"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+  gateway               = "10.10.0.1"
+  netmask               = "255.255.255.0"
+}
+
+resource "cs_virtual_machine" "foo" {
+  name                  = "vm01"
+  zone_id               = "${data.cs_zone.foo.id}"
+  service_offering_name = "small"
+  template_id           = "${data.cs_template.foo.id}"
+  network_ids           = ["${cs_network.foo.id}"]
+  expunge               = true
+}
+`),
+				Check: resource.TestCheckResourceAttrPair(
+					"cs_virtual_machine.foo", "template_id", "data.cs_template.foo", "id"),
+			},
+		},
+	})
+}
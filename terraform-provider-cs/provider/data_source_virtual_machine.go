@@ -0,0 +1,181 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func dataSourceVirtualMachine() *schema.Resource {
+	s := virtualMachineAttributes()
+	delete(s, "id")
+	for _, key := range []string{"name", "zone_id", "zone_name", "state", "group"} {
+		s[key].Optional = true
+	}
+	for key, filter := range virtualMachineFilters() {
+		if _, ok := s[key]; !ok {
+			s[key] = filter
+		}
+	}
+
+	return &schema.Resource{
+		Read:   dataSourceVirtualMachineRead,
+		Schema: withScopeFilters(s),
+	}
+}
+
+// virtualMachineFilters are the arguments searching virtual machines.
+func virtualMachineFilters() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"name_regex": &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.ValidateRegexp,
+		},
+		"network_id": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+		},
+		"tags": &schema.Schema{
+			Type:     schema.TypeMap,
+			Optional: true,
+		},
+	}
+	for _, key := range []string{"name", "zone_id", "zone_name", "state", "group"} {
+		s[key] = &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+		}
+	}
+	return s
+}
+
+// virtualMachineAttributes are the attributes of a virtual machine found by
+// a data source.
+func virtualMachineAttributes() map[string]*schema.Schema {
+	s := map[string]*schema.Schema{
+		"nic": nicSchema(),
+	}
+	for _, key := range []string{
+		"id", "name", "display_name", "zone_id", "zone_name", "state", "group",
+		"host_id", "host_name", "template_id", "template_name",
+		"template_display_text", "service_offering_id", "service_offering_name",
+		"hypervisor", "key_pair", "created", "account", "domain_id", "project_id",
+	} {
+		s[key] = &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		}
+	}
+	return s
+}
+
+func dataSourceVirtualMachineRead(d *schema.ResourceData, meta interface{}) error {
+	vms, err := searchVirtualMachines(d, meta)
+	if err != nil {
+		return err
+	}
+	if err := checkSingleResult("virtual machine", len(vms)); err != nil {
+		return err
+	}
+	vm := vms[0]
+
+	d.SetId(vm.Id.String())
+	for key, value := range flattenVirtualMachine(vm) {
+		if key != "id" {
+			d.Set(key, value)
+		}
+	}
+
+	return nil
+}
+
+// searchVirtualMachines returns the virtual machines matching the filters
+// of data source d.
+func searchVirtualMachines(d *schema.ResourceData, meta interface{}) ([]*cloudstack.VirtualMachine, error) {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListVirtualMachinesParameter()
+	getScope(d, meta).setListParam(param)
+
+	_, hasZoneId := d.GetOk("zone_id")
+	_, hasZoneName := d.GetOk("zone_name")
+	if hasZoneId || hasZoneName {
+		zoneId, err := getResourceId(d, meta, "zone")
+		if err != nil {
+			return nil, err
+		}
+		param.ZoneId.Set(zoneId)
+	}
+	if _, ok := d.GetOk("network_id"); ok {
+		networkId, err := getResourceId(d, meta, "network")
+		if err != nil {
+			return nil, err
+		}
+		param.NetworkId.Set(networkId)
+	}
+	if v, ok := d.GetOk("state"); ok {
+		param.State.Set(v)
+	}
+	if v, ok := d.GetOk("tags"); ok {
+		param.Tags = toStringMap(v.(map[string]interface{}))
+	}
+	name, hasName := d.GetOk("name")
+	if hasName {
+		param.Name.Set(name)
+	}
+
+	vms, err := config.client.ListVirtualMachines(param)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list virtualmachines: %s", err)
+	}
+
+	var nameRegexp *regexp.Regexp
+	if v, ok := d.GetOk("name_regex"); ok {
+		nameRegexp = regexp.MustCompile(v.(string))
+	}
+	group, hasGroup := d.GetOk("group")
+
+	return filter(vms, func(x interface{}) bool {
+		vm := x.(*cloudstack.VirtualMachine)
+		switch {
+		case hasName && vm.Name.String() != name.(string):
+			return false
+		case nameRegexp != nil && !nameRegexp.MatchString(vm.Name.String()):
+			return false
+		case hasGroup && vm.Group.String() != group.(string):
+			return false
+		}
+		return true
+	}).([]*cloudstack.VirtualMachine), nil
+}
+
+// flattenVirtualMachine returns the attributes of vm.
+func flattenVirtualMachine(vm *cloudstack.VirtualMachine) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                    vm.Id.String(),
+		"name":                  vm.Name.String(),
+		"display_name":          vm.DisplayName.String(),
+		"zone_id":               vm.ZoneId.String(),
+		"zone_name":             vm.ZoneName.String(),
+		"state":                 vm.State.String(),
+		"group":                 vm.Group.String(),
+		"host_id":               vm.HostId.String(),
+		"host_name":             vm.HostName.String(),
+		"template_id":           vm.TemplateId.String(),
+		"template_name":         vm.TemplateName.String(),
+		"template_display_text": vm.TemplateDisplayText.String(),
+		"service_offering_id":   vm.ServiceOfferingId.String(),
+		"service_offering_name": vm.ServiceOfferingName.String(),
+		"hypervisor":            vm.Hypervisor.String(),
+		"key_pair":              vm.KeyPair.String(),
+		"created":               vm.Created.String(),
+		"account":               vm.Account.String(),
+		"domain_id":             vm.DomainId.String(),
+		"project_id":            vm.ProjectId.String(),
+		"nic":                   flattenNics(vm),
+	}
+}
@@ -0,0 +1,171 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+// testAccAddVirtualMachines adds web01, web02 and db01 to server, web01 and
+// db01 in network net01.
+func testAccAddVirtualMachines(server *cstest.Server) (web01, net01 cstest.Object) {
+	zone := server.All("zone")[0]
+	template := server.All("template")[0]
+	domain := server.All("domain")[0]
+
+	net01 = server.Add("network", cstest.Object{
+		"name":     "net01",
+		"zoneid":   zone["id"],
+		"cidr":     "10.10.0.0/24",
+		"account":  "user1",
+		"domainid": domain["id"],
+		"tags":     []cstest.Object{},
+	})
+	net02 := server.Add("network", cstest.Object{
+		"name":     "net02",
+		"zoneid":   zone["id"],
+		"cidr":     "10.20.0.0/24",
+		"account":  "user1",
+		"domainid": domain["id"],
+		"tags":     []cstest.Object{},
+	})
+
+	vm := func(name, group, state string, network cstest.Object, tags ...cstest.Object) cstest.Object {
+		return server.Add("virtualmachine", cstest.Object{
+			"name":                name,
+			"displayname":         name,
+			"zoneid":              zone["id"],
+			"zonename":            zone["name"],
+			"templateid":          template["id"],
+			"templatename":        template["name"],
+			"templatedisplaytext": template["displaytext"],
+			"state":               state,
+			"group":               group,
+			"hostname":            "host1",
+			"hypervisor":          "KVM",
+			"account":             "user1",
+			"domainid":            domain["id"],
+			"nic": []cstest.Object{{
+				"id":          name + "-nic",
+				"ipaddress":   "10.0.0." + name[len(name)-1:],
+				"isdefault":   true,
+				"networkid":   network["id"],
+				"networkname": network["name"],
+				"traffictype": "Guest",
+			}},
+			"securitygroup": []cstest.Object{},
+			"tags":          tags,
+		})
+	}
+	web01 = vm("web01", "web", "Running", net01, cstest.Object{"key": "team", "value": "web"})
+	vm("web02", "web", "Stopped", net02, cstest.Object{"key": "team", "value": "web"})
+	vm("db01", "db", "Running", net01)
+	return web01, net01
+}
+
+func TestAccDataSourceVirtualMachine(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	web01, net01 := testAccAddVirtualMachines(server)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccProviderConfig(server, `  account = "user1"
+  domain_id = "`+web01["domainid"].(string)+`"`, `
+data "cs_virtual_machine" "foo" {
+  name = "web01"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "id", web01["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "state", "Running"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "group", "web"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "host_name", "host1"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "template_name", "CentOS 7"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "account", "user1"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "nic.#", "1"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "nic.0.ip_address", "10.0.0.1"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "nic.0.network_id", net01["id"].(string)),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_virtual_machine" "foo" {
+  account    = "user1"
+  domain_id  = "`+web01["domainid"].(string)+`"
+  network_id = "net01"
+  group      = "web"
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_virtual_machine.foo", "name", "web01"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_virtual_machine" "foo" {
+  name = "web01"
+}
+`),
+				ExpectError: regexp.MustCompile("No virtual machine matches the search"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_virtual_machine" "foo" {
+  account    = "user1"
+  domain_id  = "`+web01["domainid"].(string)+`"
+  name_regex = "^web"
+}
+`),
+				ExpectError: regexp.MustCompile("2 virtual machines match the search"),
+			},
+		},
+	})
+}
+
+func TestAccDataSourceVirtualMachines(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	web01, _ := testAccAddVirtualMachines(server)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccProviderConfig(server, `  account = "user1"
+  domain_id = "`+web01["domainid"].(string)+`"`, `
+data "cs_virtual_machines" "web" {
+  tags {
+    team = "web"
+  }
+}
+
+data "cs_virtual_machines" "running" {
+  name_regex = "01$"
+  state      = "Running"
+  zone_name  = "zone1"
+}
+
+data "cs_virtual_machines" "none" {
+  group = "cache"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.web", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.web", "ids.0", web01["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.web", "names.1", "web02"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.web", "virtual_machines.1.state", "Stopped"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.web", "virtual_machines.1.nic.0.network_name", "net02"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.running", "names.#", "2"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.running", "names.0", "web01"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.running", "names.1", "db01"),
+					resource.TestCheckResourceAttr("data.cs_virtual_machines.none", "ids.#", "0"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package cloudstack
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceVirtualMachines() *schema.Resource {
+	s := virtualMachineFilters()
+	s["ids"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+	s["names"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem:     &schema.Schema{Type: schema.TypeString},
+	}
+	s["virtual_machines"] = &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: virtualMachineAttributes(),
+		},
+	}
+
+	return &schema.Resource{
+		Read:   dataSourceVirtualMachinesRead,
+		Schema: withScopeFilters(s),
+	}
+}
+
+func dataSourceVirtualMachinesRead(d *schema.ResourceData, meta interface{}) error {
+	vms, err := searchVirtualMachines(d, meta)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(vms))
+	names := make([]string, len(vms))
+	virtualMachines := make([]map[string]interface{}, len(vms))
+	for i, vm := range vms {
+		ids[i] = vm.Id.String()
+		names[i] = vm.Name.String()
+		virtualMachines[i] = flattenVirtualMachine(vm)
+	}
+
+	// An empty search result is a result too.
+	d.SetId(strconv.Itoa(hashcode.String(strings.Join(ids, ","))))
+	d.Set("ids", ids)
+	d.Set("names", names)
+	d.Set("virtual_machines", virtualMachines)
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceZone() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceZoneRead,
+
+		Schema: map[string]*schema.Schema{
+			"id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			// network_type is Basic or Advanced.
+			"network_type": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"security_groups_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			"description": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"dns": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"internal_dns": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"domain": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"allocation_state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"local_storage_enabled": &schema.Schema{
+				Type:     schema.TypeBool,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceZoneRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListZonesParameter()
+	if v, ok := d.GetOk("id"); ok {
+		param.Id.Set(v)
+	}
+	if v, ok := d.GetOk("name"); ok {
+		param.Name.Set(v)
+	}
+	if v, ok := d.GetOk("network_type"); ok {
+		param.NetworkType.Set(v)
+	}
+
+	zones, err := config.client.ListZones(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list zones: %s", err)
+	}
+
+	if v, ok := d.GetOkExists("security_groups_enabled"); ok {
+		zones = filter(zones, func(zone interface{}) bool {
+			return zone.(*cloudstack.Zone).SecurityGroupsEnabled.Bool() == v.(bool)
+		}).([]*cloudstack.Zone)
+	}
+
+	if err := checkSingleResult("zone", len(zones)); err != nil {
+		return err
+	}
+	zone := zones[0]
+
+	d.SetId(zone.Id.String())
+	d.Set("name", zone.Name.String())
+	d.Set("network_type", zone.NetworkType.String())
+	d.Set("security_groups_enabled", zone.SecurityGroupsEnabled.Bool())
+	d.Set("description", zone.Description.String())
+	d.Set("dns", nonEmptyStrings(zone.Dns1.String(), zone.Dns2.String()))
+	d.Set("internal_dns", nonEmptyStrings(zone.InternalDns1.String(), zone.InternalDns2.String()))
+	d.Set("domain", zone.Domain.String())
+	d.Set("allocation_state", zone.AllocationState.String())
+	d.Set("local_storage_enabled", zone.LocalStorageEnabled.Bool())
+
+	return nil
+}
+
+// nonEmptyStrings returns ss without the empty strings.
+func nonEmptyStrings(ss ...string) []string {
+	var result []string
+	for _, s := range ss {
+		if s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccDataSourceZone(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	zone := server.All("zone")[0]
+	basic := server.Add("zone", cstest.Object{
+		"name":                  "zone2",
+		"networktype":           "Basic",
+		"securitygroupsenabled": true,
+		"dns1":                  "8.8.8.8",
+		"allocationstate":       "Enabled",
+		"localstorageenabled":   true,
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  name = "zone1"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "id", zone["id"].(string)),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "network_type", "Advanced"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "security_groups_enabled", "false"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "dns.#", "2"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "dns.1", "8.8.4.4"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "internal_dns.#", "1"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "domain", "cs.internal"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "allocation_state", "Enabled"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "local_storage_enabled", "false"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  id = "`+basic["id"].(string)+`"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "name", "zone2"),
+					resource.TestCheckResourceAttr("data.cs_zone.foo", "local_storage_enabled", "true"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  security_groups_enabled = true
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_zone.foo", "name", "zone2"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  network_type = "Advanced"
+}
+`),
+				Check: resource.TestCheckResourceAttr("data.cs_zone.foo", "name", "zone1"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+}
+`),
+				ExpectError: regexp.MustCompile("2 zones match the search"),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+data "cs_zone" "foo" {
+  name = "zone3"
+}
+`),
+				ExpectError: regexp.MustCompile("No zone matches the search"),
+			},
+		},
+	})
+}
@@ -0,0 +1,451 @@
+package cloudstack
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	tfconfig "github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// Generate configures the provider with the provider arguments args, which
+// fall back to the environment and the config file as in a provider block,
+// and writes a cs_* resource for each object of its account or project to
+// hcl and the terraform import commands for them to script.
+func Generate(args map[string]interface{}, hcl, script io.Writer) error {
+	p := Provider().(*schema.Provider)
+
+	raw, err := tfconfig.NewRawConfig(args)
+	if err != nil {
+		return err
+	}
+	if err := p.Configure(terraform.NewResourceConfig(raw)); err != nil {
+		return err
+	}
+
+	g := &generator{
+		config:    p.Meta().(*Config),
+		resources: p.ResourcesMap,
+		refs:      make(map[string]string),
+		sgRefs:    make(map[string]string),
+		names:     make(map[string]bool),
+	}
+	if err := g.collect(); err != nil {
+		return err
+	}
+	if err := g.writeHCL(hcl); err != nil {
+		return err
+	}
+	return g.writeScript(script)
+}
+
+// generated is an object read by the Read function of its resource.
+type generated struct {
+	resourceType string
+	name         string
+	d            *schema.ResourceData
+}
+
+type generator struct {
+	config    *Config
+	resources map[string]*schema.Resource
+	objs      []*generated
+
+	// refs maps the ids of the generated objects and sgRefs the names of
+	// the security groups to interpolations of their resources.
+	refs   map[string]string
+	sgRefs map[string]string
+	names  map[string]bool
+}
+
+// generateSkipped are the arguments left out of the generated resources:
+// the scope comes from the provider and the others are not read back.
+var generateSkipped = map[string]bool{
+	"account":         true,
+	"domain_id":       true,
+	"project_id":      true,
+	"expunge":         true,
+	"open_firewall":   true,
+	"template_filter": true,
+}
+
+// collect lists the objects in the order they depend on each other.
+func (g *generator) collect() error {
+	config := g.config
+	s := scope{
+		account:   config.Account,
+		domainId:  config.DomainId,
+		projectId: config.ProjectId,
+	}
+
+	sgParam := cloudstack.NewListSecurityGroupsParameter()
+	s.setListParam(sgParam)
+	sgs, err := config.client.ListSecurityGroups(sgParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list security groups: %s", err)
+	}
+	for _, sg := range sgs {
+		// The default group exists in every account and can't be deleted.
+		if sg.Name.String() == "default" {
+			continue
+		}
+		obj, err := g.add("cs_security_group", sg.Id.String(), sg.Name.String())
+		if err != nil {
+			return err
+		}
+		if obj != nil {
+			g.sgRefs[sg.Name.String()] = g.ref(obj, "name")
+		}
+	}
+
+	networkParam := cloudstack.NewListNetworksParameter()
+	s.setListParam(networkParam)
+	networks, err := config.client.ListNetworks(networkParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list networks: %s", err)
+	}
+	for _, network := range networks {
+		// Shared networks belong to the admins offering them.
+		if network.Type.String() == "Shared" {
+			continue
+		}
+		if _, err := g.add("cs_network", network.Id.String(), network.Name.String()); err != nil {
+			return err
+		}
+	}
+
+	vmParam := cloudstack.NewListVirtualMachinesParameter()
+	s.setListParam(vmParam)
+	vms, err := config.client.ListVirtualMachines(vmParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list virtualmachines: %s", err)
+	}
+	for _, vm := range vms {
+		if _, err := g.add("cs_virtual_machine", vm.Id.String(), vm.Name.String()); err != nil {
+			return err
+		}
+	}
+
+	volumeParam := cloudstack.NewListVolumesParameter()
+	s.setListParam(volumeParam)
+	volumes, err := config.client.ListVolumes(volumeParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list volumes: %s", err)
+	}
+	for _, volume := range volumes {
+		// Root volumes come and go with their virtual machines.
+		if volume.Type.String() == "ROOT" {
+			continue
+		}
+		if _, err := g.add("cs_volume", volume.Id.String(), volume.Name.String()); err != nil {
+			return err
+		}
+	}
+
+	ipParam := cloudstack.NewListPublicIpAddressesParameter()
+	s.setListParam(ipParam)
+	ipAddresses, err := config.client.ListPublicIpAddresses(ipParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list ipaddress: %s", err)
+	}
+	ipNames := make(map[string]string)
+	for _, ip := range ipAddresses {
+		// Source NAT addresses come and go with their networks and VPCs;
+		// rules on them still refer to the address by id.
+		if ip.IsSourceNat.Bool() {
+			ipNames[ip.Id.String()] = "ip_" + ip.IpAddress.String()
+			continue
+		}
+		obj, err := g.add("cs_ip_address", ip.Id.String(), "ip_"+ip.IpAddress.String())
+		if err != nil {
+			return err
+		}
+		if obj != nil {
+			ipNames[ip.Id.String()] = obj.name
+		}
+	}
+
+	fwParam := cloudstack.NewListFirewallRulesParameter()
+	s.setListParam(fwParam)
+	fwRules, err := config.client.ListFirewallRules(fwParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list firewall rules: %s", err)
+	}
+	for _, fwRule := range fwRules {
+		name := ruleName(ipNames[fwRule.IpAddressId.String()],
+			fwRule.Protocol.String(), fwRule.StartPort.String())
+		if _, err := g.add("cs_firewall_rule", fwRule.Id.String(), name); err != nil {
+			return err
+		}
+	}
+
+	pfParam := cloudstack.NewListPortForwardingRulesParameter()
+	s.setListParam(pfParam)
+	pfRules, err := config.client.ListPortForwardingRules(pfParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list port forwarding rules: %s", err)
+	}
+	for _, pfRule := range pfRules {
+		name := ruleName(ipNames[pfRule.IpAddressId.String()],
+			pfRule.Protocol.String(), pfRule.PublicPort.String())
+		if _, err := g.add("cs_port_forwarding_rule", pfRule.Id.String(), name); err != nil {
+			return err
+		}
+	}
+
+	lbParam := cloudstack.NewListLoadBalancerRulesParameter()
+	s.setListParam(lbParam)
+	lbs, err := config.client.ListLoadBalancerRules(lbParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list load balancer rule: %s", err)
+	}
+	for _, lb := range lbs {
+		if _, err := g.add("cs_load_balancer_rule", lb.Id.String(), lb.Name.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ruleName names the rules of an IP address after it, their protocol and
+// their first port.
+func ruleName(ipName, protocol, port string) string {
+	name := ipName + "_" + protocol
+	if port != "" {
+		name += "_" + port
+	}
+	return name
+}
+
+// add reads the object id with the resource resourceType. It returns nil if
+// the object is gone.
+func (g *generator) add(resourceType, id, name string) (*generated, error) {
+	r := g.resources[resourceType]
+	d := r.Data(&terraform.InstanceState{ID: id})
+	if err := r.Read(d, g.config); err != nil {
+		return nil, err
+	}
+	if d.Id() == "" {
+		return nil, nil
+	}
+
+	obj := &generated{
+		resourceType: resourceType,
+		name:         g.uniqueName(resourceType, name),
+		d:            d,
+	}
+	g.objs = append(g.objs, obj)
+	g.refs[id] = g.ref(obj, "id")
+	return obj, nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// uniqueName turns name into a resource name not used yet.
+func (g *generator) uniqueName(resourceType, name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || !isLetter(name[0]) {
+		name = strings.TrimPrefix(resourceType, "cs_") + "_" + name
+	}
+	name = strings.TrimSuffix(name, "_")
+
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_'
+}
+
+func (g *generator) ref(obj *generated, attr string) string {
+	return fmt.Sprintf("${%s.%s.%s}", obj.resourceType, obj.name, attr)
+}
+
+func (g *generator) writeHCL(w io.Writer) error {
+	for i, obj := range g.objs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "resource %q %q {\n", obj.resourceType, obj.name)
+		g.writeBlock(w, "  ", g.resources[obj.resourceType].Schema, obj.d.Get)
+		if _, err := fmt.Fprintln(w, "}"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) writeScript(w io.Writer) error {
+	fmt.Fprintln(w, "#!/bin/sh")
+	fmt.Fprintln(w, "set -e")
+	fmt.Fprintln(w)
+	for _, obj := range g.objs {
+		id := obj.d.Id()
+		if g.config.ProjectId != "" {
+			id = g.config.ProjectId + "/" + id
+		}
+		if _, err := fmt.Fprintf(w, "terraform import %s.%s %s\n", obj.resourceType, obj.name, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBlock writes the arguments in s, whose values are returned by get,
+// as attributes aligned like terraform fmt, followed by the nested blocks.
+func (g *generator) writeBlock(w io.Writer, indent string, s map[string]*schema.Schema, get func(string) interface{}) {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var attrs [][2]string
+	var blocks []string
+	width := 0
+	for _, k := range keys {
+		if !g.generates(s, k, get) {
+			continue
+		}
+		if _, ok := s[k].Elem.(*schema.Resource); ok {
+			blocks = append(blocks, k)
+			continue
+		}
+		if v, ok := g.value(k, s[k], get(k)); ok {
+			attrs = append(attrs, [2]string{k, v})
+			if len(k) > width {
+				width = len(k)
+			}
+		}
+	}
+
+	for _, attr := range attrs {
+		fmt.Fprintf(w, "%s%-*s = %s\n", indent, width, attr[0], attr[1])
+	}
+
+	for _, k := range blocks {
+		elem := s[k].Elem.(*schema.Resource)
+		for _, v := range toList(get(k)) {
+			m := v.(map[string]interface{})
+			fmt.Fprintf(w, "\n%s%s {\n", indent, k)
+			g.writeBlock(w, indent+"  ", elem.Schema, func(k string) interface{} {
+				return m[k]
+			})
+			fmt.Fprintf(w, "%s}\n", indent)
+		}
+	}
+}
+
+// generates tells whether the argument k is written. Of pairs like zone_id
+// and zone_name the id is written when it refers to a generated resource
+// and the name otherwise.
+func (g *generator) generates(s map[string]*schema.Schema, k string, get func(string) interface{}) bool {
+	if generateSkipped[k] || !s[k].Optional && !s[k].Required {
+		return false
+	}
+
+	if strings.HasSuffix(k, "_name") || strings.HasSuffix(k, "_names") {
+		idKey := strings.Replace(k, "_name", "_id", 1)
+		if _, ok := s[idKey]; ok {
+			return !g.allRefs(get(idKey))
+		}
+	}
+	if strings.HasSuffix(k, "_id") || strings.HasSuffix(k, "_ids") {
+		nameKey := strings.Replace(k, "_id", "_name", 1)
+		if _, ok := s[nameKey]; ok {
+			return g.allRefs(get(k)) || isEmpty(get(nameKey))
+		}
+	}
+	return true
+}
+
+// allRefs tells whether v is an id, or a set of ids, of generated resources.
+func (g *generator) allRefs(v interface{}) bool {
+	ids := toList(v)
+	if len(ids) == 0 {
+		return false
+	}
+	for _, id := range ids {
+		if _, ok := g.refs[id.(string)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the HCL of v, the value of argument k, and whether it is
+// written at all. Empty values and zeros of computed arguments or defaults
+// are left out.
+func (g *generator) value(k string, s *schema.Schema, v interface{}) (string, bool) {
+	switch s.Type {
+	case schema.TypeString:
+		if v.(string) == "" {
+			return "", false
+		}
+		return g.stringValue(k, v.(string)), true
+	case schema.TypeInt:
+		if v.(int) == 0 && s.Computed || s.Default != nil && v == s.Default {
+			return "", false
+		}
+		return strconv.Itoa(v.(int)), true
+	case schema.TypeBool:
+		if !v.(bool) && s.Computed || s.Default != nil && v == s.Default {
+			return "", false
+		}
+		return strconv.FormatBool(v.(bool)), true
+	case schema.TypeList, schema.TypeSet:
+		list := toList(v)
+		if len(list) == 0 {
+			return "", false
+		}
+		values := make([]string, len(list))
+		for i, e := range list {
+			values[i] = g.stringValue(k, e.(string))
+		}
+		sort.Strings(values)
+		return "[" + strings.Join(values, ", ") + "]", true
+	}
+	return "", false
+}
+
+// stringValue quotes v, replacing ids and security group names by
+// interpolations of their resources.
+func (g *generator) stringValue(k, v string) string {
+	if k == "security_groups" {
+		if ref, ok := g.sgRefs[v]; ok {
+			return strconv.Quote(ref)
+		}
+	} else if ref, ok := g.refs[v]; ok {
+		return strconv.Quote(ref)
+	}
+	return strconv.Quote(strings.Replace(v, "${", "$${", -1))
+}
+
+func toList(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case *schema.Set:
+		return v.List()
+	case []interface{}:
+		return v
+	case string:
+		if v != "" {
+			return []interface{}{v}
+		}
+	}
+	return nil
+}
+
+func isEmpty(v interface{}) bool {
+	return len(toList(v)) == 0
+}
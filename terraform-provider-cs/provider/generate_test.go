@@ -0,0 +1,214 @@
+package cloudstack
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// testAccGenerateConfig names its resources the way Generate does, so that
+// planning the generated configuration against its state shows no change.
+const testAccGenerateConfig = `
+resource "cs_security_group" "sg01" {
+  name = "sg01"
+
+  ingress_rule {
+    protocol   = "tcp"
+    cidr       = "0.0.0.0/0"
+    start_port = 22
+    end_port   = 22
+  }
+}
+
+resource "cs_network" "net01" {
+  name                  = "net01"
+  display_text          = "net01"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+}
+
+resource "cs_virtual_machine" "vm01" {
+  name                  = "vm01"
+  zone_name             = "zone1"
+  service_offering_name = "small"
+  template_name         = "CentOS 7"
+  network_ids           = ["${cs_network.net01.id}"]
+  user_data             = "IyEvYmluL3NoCg=="
+}
+
+resource "cs_volume" "data01" {
+  name               = "data01"
+  zone_name          = "zone1"
+  disk_offering_name = "small"
+  is_attached        = true
+  virtual_machine_id = "${cs_virtual_machine.vm01.id}"
+}
+
+resource "cs_ip_address" "ip_203_0_113_10" {
+}
+
+resource "cs_firewall_rule" "ip_203_0_113_10_tcp_80" {
+  ip_address_id = "${cs_ip_address.ip_203_0_113_10.id}"
+  protocol      = "tcp"
+  cidr_list     = ["0.0.0.0/0"]
+  start_port    = 80
+  end_port      = 80
+}
+
+resource "cs_port_forwarding_rule" "ip_203_0_113_10_tcp_2222" {
+  ip_address_id      = "${cs_ip_address.ip_203_0_113_10.id}"
+  protocol           = "tcp"
+  private_port       = 22
+  public_port        = 2222
+  virtual_machine_id = "${cs_virtual_machine.vm01.id}"
+}
+
+resource "cs_load_balancer_rule" "lb01" {
+  name                = "lb01"
+  algorithm           = "roundrobin"
+  private_port        = 80
+  public_port         = 80
+  public_ip_id        = "${cs_ip_address.ip_203_0_113_10.id}"
+  virtual_machine_ids = ["${cs_virtual_machine.vm01.id}"]
+}
+`
+
+func TestGenerate(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	zone := server.All("zone")[0]
+	server.Add("publicipaddress", cstest.Object{
+		"ipaddress":   "203.0.113.5",
+		"issourcenat": true,
+		"isstaticnat": false,
+		"state":       "Allocated",
+		"zoneid":      zone["id"],
+		"zonename":    zone["name"],
+		"account":     "admin",
+		"domainid":    server.All("domain")[0]["id"],
+		"tags":        []cstest.Object{},
+	})
+
+	var hcl, script bytes.Buffer
+	steps := []resource.TestStep{
+		resource.TestStep{
+			Config: testAccConfig(server, testAccGenerateConfig),
+			Check: func(*terraform.State) error {
+				return Generate(map[string]interface{}{
+					"end_point":  server.EndPoint(),
+					"api_key":    server.APIKey,
+					"secret_key": server.SecretKey,
+				}, &hcl, &script)
+			},
+		},
+		resource.TestStep{
+			// Config is the generated configuration, set by the check of
+			// the first step.
+			PlanOnly: true,
+		},
+	}
+	steps[0].Check = resource.ComposeTestCheckFunc(steps[0].Check,
+		func(*terraform.State) error {
+			steps[1].Config = testAccConfig(server, hcl.String())
+			return nil
+		},
+		testAccCheckGenerated(&hcl,
+			`network_ids           = ["${cs_network.net01.id}"]`,
+			`virtual_machine_id = "${cs_virtual_machine.vm01.id}"`,
+			`ip_address_id = "${cs_ip_address.ip_203_0_113_10.id}"`,
+			`virtual_machine_ids = ["${cs_virtual_machine.vm01.id}"]`,
+		),
+	)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps:     steps,
+	})
+
+	if strings.Contains(hcl.String(), "203_0_113_5") {
+		t.Errorf("Generated configuration has the source NAT address:\n%s", hcl.String())
+	}
+	for _, name := range []string{
+		"cs_security_group.sg01", "cs_network.net01", "cs_virtual_machine.vm01",
+		"cs_volume.data01", "cs_ip_address.ip_203_0_113_10",
+		"cs_firewall_rule.ip_203_0_113_10_tcp_80",
+		"cs_port_forwarding_rule.ip_203_0_113_10_tcp_2222", "cs_load_balancer_rule.lb01",
+	} {
+		if !strings.Contains(script.String(), "terraform import "+name+" ") {
+			t.Errorf("import.sh doesn't import %s:\n%s", name, script.String())
+		}
+	}
+}
+
+func testAccCheckGenerated(hcl *bytes.Buffer, want ...string) resource.TestCheckFunc {
+	return func(*terraform.State) error {
+		for _, s := range want {
+			if !strings.Contains(hcl.String(), s) {
+				return fmt.Errorf("Generated configuration lacks %s:\n%s", s, hcl.String())
+			}
+		}
+		return nil
+	}
+}
+
+func TestGenerate_project(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	project := server.All("project")[0]
+	args := fmt.Sprintf(`  project_id = "%s"`, project["id"])
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccProviderConfig(server, args, testAccNetworkConfig("net01")),
+				Check: func(s *terraform.State) error {
+					var hcl, script bytes.Buffer
+					err := Generate(map[string]interface{}{
+						"end_point":  server.EndPoint(),
+						"api_key":    server.APIKey,
+						"secret_key": server.SecretKey,
+						"project_id": project["id"],
+					}, &hcl, &script)
+					if err != nil {
+						return err
+					}
+
+					want := fmt.Sprintf("terraform import cs_network.net01 %s/%s\n",
+						project["id"], s.RootModule().Resources["cs_network.foo"].Primary.ID)
+					if !strings.Contains(script.String(), want) {
+						return fmt.Errorf("Expected %q in:\n%s", want, script.String())
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestGenerator_uniqueName(t *testing.T) {
+	g := &generator{names: make(map[string]bool)}
+
+	cases := []struct {
+		resourceType, name, want string
+	}{
+		{"cs_virtual_machine", "web01", "web01"},
+		{"cs_virtual_machine", "web01", "web01_2"},
+		{"cs_volume", "web01", "web01_3"},
+		{"cs_virtual_machine", "web.example.com", "web_example_com"},
+		{"cs_network", "10.0.0.0/24 net", "network_10_0_0_0_24_net"},
+		{"cs_security_group", "", "security_group"},
+	}
+	for _, c := range cases {
+		if got := g.uniqueName(c.resourceType, c.name); got != c.want {
+			t.Errorf("uniqueName(%q, %q) = %q, want %q", c.resourceType, c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package cloudstack
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// newHTTPClient returns the HTTP client used for API calls, set up with the
+// TLS, proxy and timeout options.
+func (c *Config) newHTTPClient() (*http.Client, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if c.ProxyURL != "" {
+		u, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("Error parse proxy_url (%s): %s", c.ProxyURL, err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+	}
+
+	if c.RequestTimeout > 0 {
+		return &http.Client{
+			Transport: &requestTimeoutTransport{timeout: c.RequestTimeout, base: transport},
+		}, nil
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// requestTimeoutTransport bounds each request sent to the server, from
+// dialing to reading the whole response. It sits below the other transports
+// so that retries, throttling and the waits between job polls don't count
+// against it.
+type requestTimeoutTransport struct {
+	timeout time.Duration
+	base    http.RoundTripper
+}
+
+func (t *requestTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelBody releases the timeout of its request once it is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		pem, err := readFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error read ca_file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("Error read ca_file: no certificate found in %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return nil, fmt.Errorf("client_cert_file and client_key_file must be specified together")
+	}
+	if c.ClientCertFile != "" {
+		certPEM, err := readFile(c.ClientCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error read client_cert_file: %s", err)
+		}
+		keyPEM, err := readFile(c.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error read client_key_file: %s", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("Error load client certificate: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func readFile(path string) ([]byte, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
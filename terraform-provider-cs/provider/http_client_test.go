@@ -0,0 +1,157 @@
+package cloudstack
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+)
+
+// testTLSServer serves the API of a simulator over TLS.
+func testTLSServer(server *cstest.Server) *httptest.Server {
+	return httptest.NewTLSServer(server.Config.Handler)
+}
+
+// testListZones configures config, which already calls the API, and lists
+// the zones.
+func testListZones(t *testing.T, config *Config) error {
+	if err := config.loadAndValidate(); err != nil {
+		return err
+	}
+	_, err := config.client.ListZones(cloudstack.NewListZonesParameter())
+	return err
+}
+
+func TestHTTPClientCAFile(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+	tlsServer := testTLSServer(server)
+	defer tlsServer.Close()
+
+	config := &Config{
+		EndPoint:  tlsServer.URL + "/client/api",
+		ApiKey:    server.APIKey,
+		SecretKey: server.SecretKey,
+	}
+	if err := testListZones(t, config); err == nil {
+		t.Fatalf("expected an error for the unknown certificate authority")
+	}
+
+	dir, err := ioutil.TempDir("", "cs-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config.CAFile = caFile
+	if err := testListZones(t, config); err != nil {
+		t.Errorf("listZones failed with ca_file: %s", err)
+	}
+}
+
+func TestHTTPClientInsecureSkipVerify(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+	tlsServer := testTLSServer(server)
+	defer tlsServer.Close()
+
+	config := &Config{
+		EndPoint:           tlsServer.URL + "/client/api",
+		ApiKey:             server.APIKey,
+		SecretKey:          server.SecretKey,
+		InsecureSkipVerify: true,
+	}
+	if err := testListZones(t, config); err != nil {
+		t.Errorf("listZones failed with insecure_skip_verify: %s", err)
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	proxied := 0
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied++
+		httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	config := &Config{
+		EndPoint:  "http://cloudstack.invalid/client/api",
+		ApiKey:    server.APIKey,
+		SecretKey: server.SecretKey,
+		ProxyURL:  proxy.URL,
+	}
+	if err := testListZones(t, config); err != nil {
+		t.Fatalf("listZones failed through the proxy: %s", err)
+	}
+	// listCapabilities and listZones
+	if proxied != 2 {
+		t.Errorf("the proxy got %d requests, expected 2", proxied)
+	}
+}
+
+func TestHTTPClientRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	config := &Config{
+		EndPoint:       server.URL,
+		ApiKey:         "apikey",
+		SecretKey:      "secretkey",
+		RequestTimeout: 50 * time.Millisecond,
+	}
+	if err := testListZones(t, config); err == nil {
+		t.Errorf("expected a timeout error")
+	}
+}
+
+func TestHTTPClientRequestTimeoutPolling(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	// The wait between job polls is longer than the request timeout.
+	config := &Config{
+		EndPoint:       server.EndPoint(),
+		ApiKey:         server.APIKey,
+		SecretKey:      server.SecretKey,
+		RequestTimeout: 50 * time.Millisecond,
+		PollInterval:   100 * time.Millisecond,
+	}
+	if err := config.loadAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+	config, _ = config.withOperation(time.Minute)
+
+	param := cloudstack.NewAssociateIpAddressParameter()
+	param.ZoneId.Set(server.All("zone")[0]["id"])
+	if _, err := config.client.AssociateIpAddress(param); err != nil {
+		t.Errorf("associateIpAddress failed: %s", err)
+	}
+}
+
+func TestHTTPClientClientCertPair(t *testing.T) {
+	config := &Config{ClientCertFile: "cert.pem"}
+	if _, err := config.newHTTPClient(); err == nil {
+		t.Errorf("expected an error for client_cert_file without client_key_file")
+	}
+}
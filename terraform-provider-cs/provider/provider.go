@@ -1,50 +1,200 @@
 package cloudstack
 
 import (
+	"time"
+
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 )
 
 // Provider returns a terraform.ResourceProvider.
 func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
+	p := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"end_point": &schema.Schema{
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 			},
 
 			"api_key": &schema.Schema{
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
 			},
 
 			"secret_key": &schema.Schema{
 				Type:     schema.TypeString,
-				Required: true,
+				Optional: true,
+			},
+
+			"username": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"password": &schema.Schema{
+				Type:      schema.TypeString,
+				Optional:  true,
+				Sensitive: true,
+			},
+
+			"domain": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"config_file": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"profile": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"ca_file": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"client_cert_file": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"client_key_file": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"insecure_skip_verify": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+
+			"proxy_url": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			// request_timeout is in seconds
+			"request_timeout": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+
+			// poll_interval and job_timeout are in seconds
+			"poll_interval": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+
+			"job_timeout": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+
+			"max_retries": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  defaultMaxRetries,
+			},
+
+			"requests_per_second": &schema.Schema{
+				Type:     schema.TypeFloat,
+				Optional: true,
+			},
+
+			"max_async_jobs": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+			},
+
+			"trace_file": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"account": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"domain_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+
+			"project_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
 			},
 		},
 
+		DataSourcesMap: map[string]*schema.Resource{
+			"cs_disk_offering":     dataSourceDiskOffering(),
+			"cs_network":           dataSourceNetwork(),
+			"cs_public_ip_address": dataSourcePublicIpAddress(),
+			"cs_service_offering":  dataSourceServiceOffering(),
+			"cs_template":          dataSourceTemplate(),
+			"cs_virtual_machine":   dataSourceVirtualMachine(),
+			"cs_virtual_machines":  dataSourceVirtualMachines(),
+			"cs_zone":              dataSourceZone(),
+		},
+
 		ResourcesMap: map[string]*schema.Resource{
+			"cs_affinity_group":       resourceAffinityGroup(),
 			"cs_firewall_rule":        resourceFirewallRule(),
 			"cs_ip_address":           resourceIpAddress(),
 			"cs_load_balancer_rule":   resourceLoadBalancerRule(),
 			"cs_network":              resourceNetwork(),
+			"cs_network_acl_list":     resourceNetworkACLList(),
+			"cs_network_acl_rule":     resourceNetworkACLRule(),
 			"cs_port_forwarding_rule": resourcePortForwardingRule(),
+			"cs_private_gateway":      resourcePrivateGateway(),
 			"cs_security_group":       resourceSecurityGroup(),
+			"cs_ssh_keypair":          resourceSSHKeyPair(),
+			"cs_static_route":         resourceStaticRoute(),
 			"cs_virtual_machine":      resourceVirtualMachine(),
 			"cs_volume":               resourceVolume(),
+			"cs_vpc":                  resourceVPC(),
 		},
+	}
 
-		ConfigureFunc: providerConfigure,
+	p.ConfigureFunc = func(d *schema.ResourceData) (interface{}, error) {
+		return providerConfigure(d, p.StopContext().Done())
 	}
+
+	return p
 }
 
-func providerConfigure(d *schema.ResourceData) (interface{}, error) {
+func providerConfigure(d *schema.ResourceData, stop <-chan struct{}) (interface{}, error) {
 	config := Config{
-		EndPoint:  d.Get("end_point").(string),
-		ApiKey:    d.Get("api_key").(string),
-		SecretKey: d.Get("secret_key").(string),
+		EndPoint:           d.Get("end_point").(string),
+		ApiKey:             d.Get("api_key").(string),
+		SecretKey:          d.Get("secret_key").(string),
+		Username:           d.Get("username").(string),
+		Password:           d.Get("password").(string),
+		Domain:             d.Get("domain").(string),
+		ConfigFile:         d.Get("config_file").(string),
+		Profile:            d.Get("profile").(string),
+		CAFile:             d.Get("ca_file").(string),
+		ClientCertFile:     d.Get("client_cert_file").(string),
+		ClientKeyFile:      d.Get("client_key_file").(string),
+		InsecureSkipVerify: d.Get("insecure_skip_verify").(bool),
+		ProxyURL:           d.Get("proxy_url").(string),
+		RequestTimeout:     time.Duration(d.Get("request_timeout").(int)) * time.Second,
+		PollInterval:       time.Duration(d.Get("poll_interval").(int)) * time.Second,
+		JobTimeout:         time.Duration(d.Get("job_timeout").(int)) * time.Second,
+		MaxRetries:         d.Get("max_retries").(int),
+		RequestsPerSecond:  d.Get("requests_per_second").(float64),
+		MaxAsyncJobs:       d.Get("max_async_jobs").(int),
+		TraceFile:          d.Get("trace_file").(string),
+		Account:            d.Get("account").(string),
+		DomainId:           d.Get("domain_id").(string),
+		ProjectId:          d.Get("project_id").(string),
+		stop:               stop,
 	}
 
 	if err := config.loadAndValidate(); err != nil {
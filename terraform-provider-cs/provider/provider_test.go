@@ -0,0 +1,111 @@
+package cloudstack
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+var testAccProviders = map[string]terraform.ResourceProvider{
+	"cs": Provider(),
+}
+
+func init() {
+	// The simulator finishes jobs right away.
+	defaultPollInterval = 10 * time.Millisecond
+}
+
+func TestProvider(t *testing.T) {
+	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// TestProvider_dataSources checks that no data source argument is ForceNew,
+// which only means something for resources.
+func TestProvider_dataSources(t *testing.T) {
+	for name, r := range Provider().(*schema.Provider).DataSourcesMap {
+		for key, s := range r.Schema {
+			if s.ForceNew {
+				t.Errorf("%s: %s is ForceNew", name, key)
+			}
+		}
+	}
+}
+
+// testAccConfig returns config with a provider block pointing at server.
+func testAccConfig(server *cstest.Server, config string) string {
+	return testAccProviderConfig(server, "", config)
+}
+
+// testAccProviderConfig is testAccConfig with more provider arguments.
+func testAccProviderConfig(server *cstest.Server, args, config string) string {
+	return fmt.Sprintf(`
+provider "cs" {
+  end_point  = "%s"
+  api_key    = "%s"
+  secret_key = "%s"
+%s
+}
+`, server.EndPoint(), server.APIKey, server.SecretKey, args) + config
+}
+
+// testAccCheckDestroy checks that server holds no objects of kind.
+func testAccCheckDestroy(server *cstest.Server, kind string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if objs := server.All(kind); len(objs) > 0 {
+			return fmt.Errorf("%d %s objects still exist", len(objs), kind)
+		}
+		return nil
+	}
+}
+
+// testAccCheckSameId checks that the resource named n keeps the id it had
+// when first checked, so that it has been updated in place.
+func testAccCheckSameId(n string, id *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if *id == "" {
+			*id = rs.Primary.ID
+		}
+		if rs.Primary.ID != *id {
+			return fmt.Errorf("%s was replaced, id %s is not %s", n, rs.Primary.ID, *id)
+		}
+		return nil
+	}
+}
+
+// testAccCheckExists checks that the object of kind in the state of the
+// resource named n exists on server.
+func testAccCheckExists(server *cstest.Server, kind, n string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if server.Get(kind, rs.Primary.ID) == nil {
+			return fmt.Errorf("%s %s does not exist", kind, rs.Primary.ID)
+		}
+		return nil
+	}
+}
+
+// testAccImportStep imports the resource named n from server and verifies
+// it against the state, except for the ignore fields.
+func testAccImportStep(server *cstest.Server, n string, ignore ...string) resource.TestStep {
+	return resource.TestStep{
+		Config:                  testAccConfig(server, ""),
+		ResourceName:            n,
+		ImportState:             true,
+		ImportStateVerify:       true,
+		ImportStateVerifyIgnore: ignore,
+	}
+}
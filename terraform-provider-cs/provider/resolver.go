@@ -0,0 +1,316 @@
+package cloudstack
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+var uuidRegexp = regexp.MustCompile(
+	`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isUUID reports whether s is an id rather than a name.
+func isUUID(s string) bool {
+	return uuidRegexp.MatchString(s)
+}
+
+// lookup narrows down the objects a name may refer to: those in the scope
+// of the resource and, for templates and ISOs, in its zone, for its
+// hypervisor and listed by its template filter.
+type lookup struct {
+	scope
+
+	zoneId         string
+	templateFilter string
+	hypervisor     string
+}
+
+// resolver looks up the id of a named object of one resource type.
+type resolver struct {
+	// list returns the objects which may be named name.
+	list func(client *cloudstack.Client, name string, l lookup) (interface{}, error)
+
+	// names are the fields matched against the name, Name by default.
+	names []string
+
+	// id is the field holding the id, Id by default.
+	id string
+}
+
+// resolvers holds the resolver of every resource type, keyed by the prefix
+// of its _id and _name arguments.
+var resolvers = map[string]resolver{
+	"zone": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListZonesParameter()
+		param.Name.Set(name)
+		return client.ListZones(param)
+	}},
+	"pod": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListPodsParameter()
+		param.Name.Set(name)
+		return client.ListPods(param)
+	}},
+	"cluster": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListClustersParameter()
+		param.Name.Set(name)
+		return client.ListClusters(param)
+	}},
+	"host": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListHostsParameter()
+		param.Name.Set(name)
+		return client.ListHosts(param)
+	}},
+	"physical_network": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListPhysicalNetworksParameter()
+		param.Name.Set(name)
+		return client.ListPhysicalNetworks(param)
+	}},
+	"service_offering": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListServiceOfferingsParameter()
+		param.Name.Set(name)
+		return client.ListServiceOfferings(param)
+	}},
+	"disk_offering": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListDiskOfferingsParameter()
+		param.Name.Set(name)
+		return client.ListDiskOfferings(param)
+	}},
+	"network_offering": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListNetworkOfferingsParameter()
+		param.Name.Set(name)
+		return client.ListNetworkOfferings(param)
+	}},
+	"vpc_offering": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListVPCOfferingsParameter()
+		param.Name.Set(name)
+		return client.ListVPCOfferings(param)
+	}},
+	"template": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := newListTemplatesParameter(l)
+		param.Name.Set(name)
+		return client.ListTemplates(param)
+	}},
+	"iso": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListIsosParameter()
+		filter := l.templateFilter
+		if filter == "" {
+			filter = "executable"
+		}
+		param.IsoFilter.Set(filter)
+		param.Name.Set(name)
+		if l.zoneId != "" {
+			param.ZoneId.Set(l.zoneId)
+		}
+		if l.projectId != "" {
+			param.ProjectId.Set(l.projectId)
+		}
+		return client.ListIsos(param)
+	}},
+	"domain": {names: []string{"Name", "Path"}, list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListDomainsParameter()
+		param.ListAll.Set(true)
+		return client.ListDomains(param)
+	}},
+	"account": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListAccountsParameter()
+		param.Name.Set(name)
+		param.ListAll.Set(true)
+		if l.domainId != "" {
+			param.DomainId.Set(l.domainId)
+		}
+		return client.ListAccounts(param)
+	}},
+	"project": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListProjectsParameter()
+		param.Name.Set(name)
+		param.ListAll.Set(true)
+		return client.ListProjects(param)
+	}},
+	"network": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListNetworksParameter()
+		l.setListParam(param)
+		return client.ListNetworks(param)
+	}},
+	"vpc": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListVPCsParameter()
+		param.Name.Set(name)
+		l.setListParam(param)
+		return client.ListVPCs(param)
+	}},
+	"network_acl": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListNetworkACLListsParameter()
+		param.Name.Set(name)
+		l.setListParam(param)
+		return client.ListNetworkACLLists(param)
+	}},
+	"security_group": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListSecurityGroupsParameter()
+		param.SecurityGroupName.Set(name)
+		l.setListParam(param)
+		return client.ListSecurityGroups(param)
+	}},
+	"affinity_group": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListAffinityGroupsParameter()
+		param.Name.Set(name)
+		l.setListParam(param)
+		return client.ListAffinityGroups(param)
+	}},
+	// Key pairs are referred to by name.
+	"ssh_key_pair": {id: "Name", list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListSSHKeyPairsParameter()
+		param.Name.Set(name)
+		l.setListParam(param)
+		return client.ListSSHKeyPairs(param)
+	}},
+	"snapshot": {list: func(client *cloudstack.Client, name string, l lookup) (interface{}, error) {
+		param := cloudstack.NewListSnapshotsParameter()
+		param.Name.Set(name)
+		l.setListParam(param)
+		return client.ListSnapshots(param)
+	}},
+}
+
+// templateFilters are the values of the templatefilter of listTemplates.
+var templateFilters = []string{
+	"featured", "self", "selfexecutable", "sharedexecutable",
+	"executable", "community", "all",
+}
+
+// newListTemplatesParameter returns a parameter listing the templates of l,
+// the executable ones unless l selects a template filter.
+func newListTemplatesParameter(l lookup) *cloudstack.ListTemplatesParameter {
+	filter := l.templateFilter
+	if filter == "" {
+		filter = "executable"
+	}
+	param := cloudstack.NewListTemplatesParameter(filter)
+	if l.zoneId != "" {
+		param.ZoneId.Set(l.zoneId)
+	}
+	if l.hypervisor != "" {
+		param.Hypervisor.Set(l.hypervisor)
+	}
+	if l.projectId != "" {
+		param.ProjectId.Set(l.projectId)
+	}
+	return param
+}
+
+// nameToID returns the id of the object of resourcetype named name. A UUID
+// is returned as it is, so that either can be given.
+func nameToID(client *cloudstack.Client, resourcetype, name string, l lookup) (string, error) {
+	resourcetype = strings.ToLower(resourcetype)
+
+	r, ok := resolvers[resourcetype]
+	if !ok {
+		return "", fmt.Errorf("Can't convert name of %s to id", resourcetype)
+	}
+	if isUUID(name) && r.id == "" {
+		return name, nil
+	}
+
+	objs, err := r.list(client, name, l)
+	if err != nil {
+		return "", fmt.Errorf("Failed to list %s '%s': %s", resourcetype, name, err)
+	}
+
+	matched := filter(toInterfaceSlice(objs), func(obj interface{}) bool {
+		return r.matchName(obj, name)
+	}).([]interface{})
+
+	return r.objectId(resourcetype, name, matched)
+}
+
+func (r resolver) matchName(obj interface{}, name string) bool {
+	if len(r.names) == 0 {
+		return equalName(obj, name)
+	}
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	for _, field := range r.names {
+		if v.FieldByName(field).Interface().(cloudstack.NullString).String() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// objectId returns the id of the objects matching name, which may be listed
+// more than once, e.g. a template for every zone. When they are different
+// objects the error describes each candidate.
+func (r resolver) objectId(resourcetype, name string, objs []interface{}) (string, error) {
+	idField := r.id
+	if idField == "" {
+		idField = "Id"
+	}
+
+	var ids []string
+	candidates := map[string]string{}
+	for _, obj := range objs {
+		v := reflect.Indirect(reflect.ValueOf(obj))
+		var id string
+		switch f := v.FieldByName(idField).Interface().(type) {
+		case cloudstack.ID:
+			id = f.String()
+		case cloudstack.NullString:
+			id = f.String()
+		}
+		if _, ok := candidates[id]; !ok {
+			ids = append(ids, id)
+		}
+		candidates[id] = describeObject(v, candidates[id])
+	}
+
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("No %s named %s is found", resourcetype, name)
+	case 1:
+		return ids[0], nil
+	}
+
+	descriptions := make([]string, len(ids))
+	for i, id := range ids {
+		descriptions[i] = fmt.Sprintf("%s (%s)", id, candidates[id])
+	}
+	return "", fmt.Errorf("%d %ss are named %s: %s", len(ids), resourcetype, name,
+		strings.Join(descriptions, ", "))
+}
+
+// describeObject adds the owner and location of the object v to the
+// description of an earlier listing of it, if any.
+func describeObject(v reflect.Value, description string) string {
+	var parts []string
+	if description != "" {
+		parts = strings.Split(description, ", ")
+	}
+	seen := map[string]bool{}
+	for _, part := range parts {
+		seen[part] = true
+	}
+
+	for _, field := range []struct{ name, label string }{
+		{"Account", "account"},
+		{"Domain", "domain"},
+		{"Project", "project"},
+		{"TemplateType", "type"},
+		{"Hypervisor", "hypervisor"},
+		{"ZoneName", "zone"},
+	} {
+		f := v.FieldByName(field.name)
+		if !f.IsValid() {
+			continue
+		}
+		value, ok := f.Interface().(cloudstack.NullString)
+		if !ok || value.String() == "" {
+			continue
+		}
+		part := field.label + " " + value.String()
+		if !seen[part] {
+			seen[part] = true
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,155 @@
+package cloudstack
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+)
+
+func TestNameToID(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	u, _ := url.Parse(server.EndPoint())
+	client, err := cloudstack.NewClient(u, server.APIKey, server.SecretKey, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owned := func(obj cstest.Object) cstest.Object {
+		obj["account"] = "admin"
+		obj["domainid"] = server.All("domain")[0]["id"]
+		return obj
+	}
+	server.Add("vpc", owned(cstest.Object{"name": "vpc1"}))
+	server.Add("securitygroup", owned(cstest.Object{"name": "sg1"}))
+	server.Add("affinitygroup", owned(cstest.Object{"name": "ag1"}))
+	server.Add("sshkeypair", owned(cstest.Object{"name": "key1", "fingerprint": "aa:bb"}))
+	server.Add("snapshot", owned(cstest.Object{"name": "snap1"}))
+
+	cases := []struct {
+		resourcetype, name, kind string
+	}{
+		{"zone", "zone1", "zone"},
+		{"pod", "pod1", "pod"},
+		{"cluster", "cluster1", "cluster"},
+		{"host", "host1", "host"},
+		{"service_offering", "small", "serviceoffering"},
+		{"disk_offering", "custom", "diskoffering"},
+		{"network_offering", "DefaultIsolatedNetworkOfferingWithSourceNatService", "networkoffering"},
+		{"vpc_offering", "Default VPC offering", "vpcoffering"},
+		{"template", "CentOS 7", "template"},
+		{"iso", "TinyLinux", "iso"},
+		{"domain", "dev", "domain"},
+		{"domain", "ROOT/dev", "domain"},
+		{"account", "user1", "account"},
+		{"project", "project1", "project"},
+		{"vpc", "vpc1", "vpc"},
+		{"network_acl", "default_deny", "networkacllist"},
+		{"security_group", "sg1", "securitygroup"},
+		{"affinity_group", "ag1", "affinitygroup"},
+		{"snapshot", "snap1", "snapshot"},
+	}
+	for _, c := range cases {
+		id, err := nameToID(client, c.resourcetype, c.name, lookup{})
+		if err != nil {
+			t.Errorf("nameToID(%s, %s) failed: %s", c.resourcetype, c.name, err)
+			continue
+		}
+		obj := server.Get(c.kind, id)
+		if obj == nil || (obj["name"] != c.name && obj["path"] != c.name) {
+			t.Errorf("nameToID(%s, %s) returned %s, which is %v", c.resourcetype, c.name, id, obj)
+		}
+	}
+
+	// Key pairs have no id but their name.
+	if id, err := nameToID(client, "ssh_key_pair", "key1", lookup{}); err != nil || id != "key1" {
+		t.Errorf("nameToID(ssh_key_pair, key1) = %q, %v, expected key1", id, err)
+	}
+
+	// A UUID is taken as the id without a lookup.
+	zone := server.All("zone")[0]
+	calls := server.Calls("listZones")
+	if id, err := nameToID(client, "zone", zone["id"].(string), lookup{}); err != nil || id != zone["id"] {
+		t.Errorf("nameToID(zone, %s) = %q, %v", zone["id"], id, err)
+	}
+	if server.Calls("listZones") != calls {
+		t.Errorf("nameToID looked up a UUID")
+	}
+
+	if _, err := nameToID(client, "service_offering", "huge", lookup{}); err == nil {
+		t.Errorf("nameToID succeeded for an unknown service offering, expected an error")
+	}
+	if _, err := nameToID(client, "router", "r-1-VM", lookup{}); err == nil {
+		t.Errorf("nameToID succeeded for an unknown resource type, expected an error")
+	}
+}
+
+func TestNameToID_template(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	u, _ := url.Parse(server.EndPoint())
+	client, err := cloudstack.NewClient(u, server.APIKey, server.SecretKey, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone1 := server.All("zone")[0]
+	zone2 := server.Add("zone", cstest.Object{"name": "zone2", "networktype": "Advanced"})
+	template := func(zone cstest.Object, account, hypervisor string, featured bool) cstest.Object {
+		return cstest.Object{
+			"name":         "Ubuntu",
+			"zoneid":       zone["id"],
+			"zonename":     zone["name"],
+			"account":      account,
+			"domain":       "ROOT",
+			"hypervisor":   hypervisor,
+			"templatetype": "USER",
+			"isfeatured":   featured,
+			"ispublic":     true,
+		}
+	}
+	featured := server.Add("template", template(zone1, "system", "KVM", true))
+	own := server.Add("template", template(zone2, "admin", "XenServer", false))
+	community := server.Add("template", template(zone1, "user1", "VMware", false))
+	// The featured template is also listed for the second zone.
+	server.Add("template", template(zone2, "system", "KVM", true))["id"] = featured["id"]
+
+	_, err = nameToID(client, "template", "Ubuntu", lookup{})
+	if err == nil {
+		t.Fatalf("nameToID succeeded for an ambiguous template name, expected an error")
+	}
+	for _, obj := range []cstest.Object{featured, own, community} {
+		if !strings.Contains(err.Error(), obj["id"].(string)) {
+			t.Errorf("The error %q does not mention the candidate %s", err, obj["id"])
+		}
+	}
+	for _, s := range []string{"3 templates", "account user1", "hypervisor VMware", "zone zone1, zone zone2"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("The error %q does not mention %q", err, s)
+		}
+	}
+
+	cases := []struct {
+		l  lookup
+		id interface{}
+	}{
+		{lookup{zoneId: zone2["id"].(string), hypervisor: "XenServer"}, own["id"]},
+		{lookup{templateFilter: "self"}, own["id"]},
+		{lookup{templateFilter: "featured"}, featured["id"]},
+		{lookup{templateFilter: "community", zoneId: zone1["id"].(string)}, community["id"]},
+		{lookup{hypervisor: "KVM"}, featured["id"]},
+	}
+	for _, c := range cases {
+		id, err := nameToID(client, "template", "Ubuntu", c.l)
+		if err != nil {
+			t.Errorf("nameToID(template, Ubuntu, %+v) failed: %s", c.l, err)
+		} else if id != c.id {
+			t.Errorf("nameToID(template, Ubuntu, %+v) = %s, expected %s", c.l, id, c.id)
+		}
+	}
+}
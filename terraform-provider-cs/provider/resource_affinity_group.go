@@ -0,0 +1,150 @@
+package cloudstack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceAffinityGroup() *schema.Resource {
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourceAffinityGroupCreate),
+		Read:     resourceAffinityGroupRead,
+		Delete:   withTimeout(schema.TimeoutDelete, resourceAffinityGroupDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: withScope(map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// type is one of those listed by listAffinityGroupTypes, like
+			// "host anti-affinity" and "host affinity".
+			"type": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"description": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"virtual_machine_ids": &schema.Schema{
+				Type:     schema.TypeSet,
+				Computed: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Set: func(v interface{}) int {
+					return hashcode.String(v.(string))
+				},
+			},
+		}),
+	}
+}
+
+func resourceAffinityGroupCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	typ := d.Get("type").(string)
+	types, err := config.client.ListAffinityGroupTypes(cloudstack.NewListAffinityGroupTypesParameter())
+	if err != nil {
+		return fmt.Errorf("Failed to list affinity group types: %s", err)
+	}
+	available := false
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = t.Type.String()
+		available = available || names[i] == typ
+	}
+	if !available {
+		return fmt.Errorf("Affinity group type %q is not available, choose one of %s",
+			typ, strings.Join(names, ", "))
+	}
+
+	param := cloudstack.NewCreateAffinityGroupParameter(d.Get("name").(string), typ)
+	if d.Get("description").(string) != "" {
+		param.Description.Set(d.Get("description"))
+	}
+
+	getScope(d, meta).setParam(param)
+
+	group, err := config.client.CreateAffinityGroup(param)
+	if err != nil {
+		return fmt.Errorf("Error create affinity group: %s", err)
+	}
+
+	d.SetId(group.Id.String())
+
+	return resourceAffinityGroupRead(d, meta)
+}
+
+func resourceAffinityGroupRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListAffinityGroupsParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	groups, err := config.client.ListAffinityGroups(param)
+
+	if err != nil {
+		param = cloudstack.NewListAffinityGroupsParameter()
+		getScope(d, meta).setListParam(param)
+		groups, err = config.client.ListAffinityGroups(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list affinity groups: %s", err)
+		}
+
+		fn := func(group interface{}) bool {
+			return group.(*cloudstack.AffinityGroup).Id.String() == d.Id()
+		}
+		groups = filter(groups, fn).([]*cloudstack.AffinityGroup)
+	}
+
+	if len(groups) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	group := groups[0]
+	readScope(d, group)
+
+	d.Set("name", group.Name.String())
+	d.Set("type", group.Type.String())
+	d.Set("description", group.Description.String())
+	d.Set("virtual_machine_ids", group.VirtualMachineIds)
+
+	return nil
+}
+
+func resourceAffinityGroupDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceAffinityGroupRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteAffinityGroupParameter()
+	param.Id.Set(d.Id())
+	_, err := config.client.DeleteAffinityGroup(param)
+	if err != nil {
+		return fmt.Errorf("Error delete affinity group: %s", err)
+	}
+	return resourceAffinityGroupRead(d, meta)
+}
@@ -0,0 +1,231 @@
+package cloudstack
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccAffinityGroup_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "affinitygroup"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig(
+					`affinity_group_ids = ["${cs_affinity_group.foo.id}"]`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "affinitygroup", "cs_affinity_group.foo"),
+					resource.TestCheckResourceAttr("cs_affinity_group.foo", "type", "host anti-affinity"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "affinity_group_ids.#", "1"),
+					resource.TestCheckNoResourceAttr("cs_virtual_machine.foo", "affinity_group_names.#"),
+					testAccCheckAffinityGroupCalls(server, 0),
+				),
+			},
+			testAccImportStep(server, "cs_affinity_group.foo", "virtual_machine_ids"),
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig(
+					`affinity_group_names = ["db", "db-hosts"]`)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "affinity_group_names.#", "2"),
+					testAccCheckAffinityGroupCalls(server, 1),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig(
+					`affinity_group_names = ["db", "db-host"]`)),
+				ExpectError: regexp.MustCompile(`No affinity_group named db-host is found`),
+			},
+			resource.TestStep{
+				// The virtual machine was left running by the failed
+				// update, and leaves every group now.
+				Config: testAccConfig(server, testAccAffinityGroupConfig("")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "affinity_group_names.#", "0"),
+					testAccCheckAffinityGroupCalls(server, 2),
+					func(s *terraform.State) error {
+						vm := server.Get("virtualmachine", s.RootModule().Resources["cs_virtual_machine.foo"].Primary.ID)
+						if groups := vm["affinitygroup"].([]cstest.Object); len(groups) != 0 {
+							return fmt.Errorf("Virtual machine is in %d affinity groups, expected none", len(groups))
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccAffinityGroup_import(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig(
+					`affinity_group_names = ["db"]`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckAffinityGroupImportDiff("cs_virtual_machine.foo",
+						map[string]interface{}{"affinity_group_names": []interface{}{"db"}}, false),
+					testAccCheckAffinityGroupImportDiff("cs_virtual_machine.foo",
+						map[string]interface{}{"affinity_group_names": []interface{}{}}, true),
+				),
+			},
+		},
+	})
+}
+
+func TestAccAffinityGroup_startFails(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	fail := func(text string) cstest.HandlerFunc {
+		return func(*cstest.Server, url.Values) (cstest.Object, error) {
+			return nil, cstest.Errorf(text)
+		}
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig("")),
+				Check: func(*terraform.State) error {
+					server.Handle("updateVMAffinityGroup", true, fail("update failed"))
+					server.Handle("startVirtualMachine", true, fail("start failed"))
+					return nil
+				},
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccAffinityGroupConfig(
+					`affinity_group_names = ["db"]`)),
+				ExpectError: regexp.MustCompile(`update failed, and error start it again: .*start failed`),
+			},
+		},
+	})
+}
+
+// testAccCheckAffinityGroupImportDiff imports the virtual machine of
+// resource n and checks whether its affinity groups differ from those of
+// the configuration raw.
+func testAccCheckAffinityGroupImportDiff(n string, raw map[string]interface{}, changed bool) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		r := resourceVirtualMachine()
+		meta := testAccProviders["cs"].(*schema.Provider).Meta()
+
+		d := r.Data(&terraform.InstanceState{ID: s.RootModule().Resources[n].Primary.ID})
+		imported, err := r.Importer.State(d, meta)
+		if err != nil {
+			return err
+		}
+		if err := r.Read(imported[0], meta); err != nil {
+			return err
+		}
+
+		c, err := config.NewRawConfig(raw)
+		if err != nil {
+			return err
+		}
+		diff, err := r.Diff(imported[0].State(), terraform.NewResourceConfig(c), meta)
+		if err != nil {
+			return err
+		}
+		var keys []string
+		if diff != nil {
+			for k := range diff.Attributes {
+				if strings.HasPrefix(k, "affinity_group") {
+					keys = append(keys, k)
+				}
+			}
+		}
+		if changed != (len(keys) > 0) {
+			return fmt.Errorf("Imported virtual machine differs in %v from %v, expected a change: %t",
+				keys, raw, changed)
+		}
+		return nil
+	}
+}
+
+func testAccAffinityGroupConfig(vmArgs string) string {
+	return `
+resource "cs_affinity_group" "foo" {
+  name        = "db"
+  type        = "host anti-affinity"
+  description = "database replicas"
+}
+
+resource "cs_affinity_group" "bar" {
+  name = "db-hosts"
+  type = "host affinity"
+}
+
+resource "cs_network" "foo" {
+  name                  = "net01"
+  display_text          = "net01"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+}
+
+resource "cs_virtual_machine" "foo" {
+  name                  = "db01"
+  zone_name             = "zone1"
+  service_offering_name = "small"
+  template_name         = "CentOS 7"
+  network_ids           = ["${cs_network.foo.id}"]
+  expunge               = true
+  ` + vmArgs + `
+  depends_on            = ["cs_affinity_group.bar"]
+}
+`
+}
+
+// testAccCheckAffinityGroupCalls checks that the affinity groups were
+// updated n times, each time while the virtual machine was stopped, and
+// that it runs again.
+func testAccCheckAffinityGroupCalls(server *cstest.Server, n int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		for _, command := range []string{"updateVMAffinityGroup", "stopVirtualMachine", "startVirtualMachine"} {
+			if calls := server.Calls(command); calls != n {
+				return fmt.Errorf("%s was called %d times, expected %d", command, calls, n)
+			}
+		}
+		vm := server.Get("virtualmachine", s.RootModule().Resources["cs_virtual_machine.foo"].Primary.ID)
+		if vm["state"] != "Running" {
+			return fmt.Errorf("Virtual machine is %s, expected Running", vm["state"])
+		}
+		return nil
+	}
+}
+
+func TestAccAffinityGroup_invalidType(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_affinity_group" "foo" {
+  name = "db"
+  type = "anti-affinity"
+}
+`),
+				ExpectError: regexp.MustCompile(`Affinity group type "anti-affinity" is not available, choose one of host anti-affinity, host affinity`),
+			},
+		},
+	})
+}
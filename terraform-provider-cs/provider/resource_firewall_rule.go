@@ -13,11 +13,17 @@ import (
 
 func resourceFirewallRule() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceFirewallRuleCreate,
-		Read:   resourceFirewallRuleRead,
-		Delete: resourceFirewallRuleDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceFirewallRuleCreate),
+		Read:     resourceFirewallRuleRead,
+		Delete:   withTimeout(schema.TimeoutDelete, resourceFirewallRuleDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"ip_address_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -64,7 +70,7 @@ func resourceFirewallRule() *schema.Resource {
 				Computed: true,
 				ForceNew: true,
 			},
-		},
+		}),
 	}
 }
 
@@ -89,7 +95,7 @@ func resourceFirewallRuleCreate(d *schema.ResourceData, meta interface{}) error
 		param.IcmpType.Set(d.Get("icmp_type"))
 	} else {
 		param.StartPort.Set(d.Get("start_port"))
-		param.EndPort.Set(d.Get("start_port"))
+		param.EndPort.Set(d.Get("end_port"))
 	}
 
 	fwRule, err := config.client.CreateFirewallRule(param)
@@ -106,11 +112,13 @@ func resourceFirewallRuleRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
 	param := cloudstack.NewListFirewallRulesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 
 	fwRules, err := config.client.ListFirewallRules(param)
 	if err != nil {
 		param = cloudstack.NewListFirewallRulesParameter()
+		getScope(d, meta).setListParam(param)
 		fwRules, err = config.client.ListFirewallRules(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list firewall rule: %s", err)
@@ -128,6 +136,10 @@ func resourceFirewallRuleRead(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	fwRule := fwRules[0]
+	readScope(d, fwRule)
+
+	d.Set("ip_address_id", fwRule.IpAddressId.String())
+	d.Set("protocol", fwRule.Protocol.String())
 
 	var cidrList []interface{}
 	for _, s := range strings.Split(fwRule.CidrList.String(), ",") {
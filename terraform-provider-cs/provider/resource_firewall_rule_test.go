@@ -0,0 +1,41 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccFirewallRule_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "firewallrule"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_ip_address" "foo" {
+}
+
+resource "cs_firewall_rule" "foo" {
+  ip_address_id = "${cs_ip_address.foo.id}"
+  protocol      = "tcp"
+  cidr_list     = ["0.0.0.0/0"]
+  start_port    = 8000
+  end_port      = 8080
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "firewallrule", "cs_firewall_rule.foo"),
+					resource.TestCheckResourceAttr("cs_firewall_rule.foo", "start_port", "8000"),
+					resource.TestCheckResourceAttr("cs_firewall_rule.foo", "end_port", "8080"),
+					resource.TestCheckResourceAttr("cs_firewall_rule.foo", "cidr_list.#", "1"),
+				),
+			},
+			testAccImportStep(server, "cs_firewall_rule.foo"),
+		},
+	})
+}
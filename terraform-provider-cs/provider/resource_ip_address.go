@@ -10,12 +10,19 @@ import (
 
 func resourceIpAddress() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceIpAddressCreate,
-		Read:   resourceIpAddressRead,
-		Update: resourceIpAddressUpdate,
-		Delete: resourceIpAddressDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceIpAddressCreate),
+		Read:     resourceIpAddressRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceIpAddressUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceIpAddressDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"zone_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
@@ -43,7 +50,7 @@ func resourceIpAddress() *schema.Resource {
 				Optional: true,
 				Computed: true,
 			},
-		},
+		}),
 	}
 }
 
@@ -55,6 +62,8 @@ func resourceIpAddressCreate(d *schema.ResourceData, meta interface{}) error {
 		param.ZoneId.Set(d.Get("zone_id"))
 	}
 
+	getScope(d, meta).setParam(param)
+
 	ipAddress, err := config.client.AssociateIpAddress(param)
 	if err != nil {
 		return fmt.Errorf("Error associate ipaddress: %s", err)
@@ -69,11 +78,13 @@ func resourceIpAddressRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
 	param := cloudstack.NewListPublicIpAddressesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 
 	ipAddresses, err := config.client.ListPublicIpAddresses(param)
 	if err != nil {
 		param = cloudstack.NewListPublicIpAddressesParameter()
+		getScope(d, meta).setListParam(param)
 		ipAddresses, err = config.client.ListPublicIpAddresses(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list ipaddress: %s", err)
@@ -91,6 +102,7 @@ func resourceIpAddressRead(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	ipAddress := ipAddresses[0]
+	readScope(d, ipAddress)
 
 	d.Set("zone_id", ipAddress.ZoneId.String())
 	d.Set("ip_address", ipAddress.IpAddress.String())
@@ -0,0 +1,45 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccIpAddress_staticNat(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "publicipaddress"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVirtualMachineConfig("vm01")+`
+resource "cs_ip_address" "foo" {
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "publicipaddress", "cs_ip_address.foo"),
+					resource.TestCheckResourceAttr("cs_ip_address.foo", "ip_address", "203.0.113.10"),
+					resource.TestCheckResourceAttr("cs_ip_address.foo", "is_static_nat", "false"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVirtualMachineConfig("vm01")+`
+resource "cs_ip_address" "foo" {
+  is_static_nat      = true
+  virtual_machine_id = "${cs_virtual_machine.foo.id}"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_ip_address.foo", "is_static_nat", "true"),
+					resource.TestCheckResourceAttrPair(
+						"cs_ip_address.foo", "virtual_machine_id", "cs_virtual_machine.foo", "id"),
+				),
+			},
+			testAccImportStep(server, "cs_ip_address.foo"),
+		},
+	})
+}
@@ -2,6 +2,7 @@ package cloudstack
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/atsaki/golang-cloudstack-library"
 	"github.com/hashicorp/terraform/helper/hashcode"
@@ -10,12 +11,19 @@ import (
 
 func resourceLoadBalancerRule() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceLoadBalancerRuleCreate,
-		Read:   resourceLoadBalancerRuleRead,
-		Update: resourceLoadBalancerRuleUpdate,
-		Delete: resourceLoadBalancerRuleDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceLoadBalancerRuleCreate),
+		Read:     resourceLoadBalancerRuleRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceLoadBalancerRuleUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceLoadBalancerRuleDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"algorithm": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -63,7 +71,7 @@ func resourceLoadBalancerRule() *schema.Resource {
 					return hashcode.String(v.(string))
 				},
 			},
-		},
+		}),
 	}
 }
 
@@ -84,6 +92,8 @@ func resourceLoadBalancerRuleCreate(d *schema.ResourceData, meta interface{}) er
 		param.PublicIpId.Set(d.Get("public_ip_id"))
 	}
 
+	getScope(d, meta).setParam(param)
+
 	lb, err := config.client.CreateLoadBalancerRule(param)
 	if err != nil {
 		return fmt.Errorf("Error create load balancer rule: %s", err)
@@ -169,11 +179,13 @@ func resourceLoadBalancerRuleRead(d *schema.ResourceData, meta interface{}) erro
 	config := meta.(*Config)
 
 	param := cloudstack.NewListLoadBalancerRulesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	lbs, err := config.client.ListLoadBalancerRules(param)
 
 	if err != nil {
 		param = cloudstack.NewListLoadBalancerRulesParameter()
+		getScope(d, meta).setListParam(param)
 		lbs, err = config.client.ListLoadBalancerRules(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list load balancer rule: %s", err)
@@ -191,6 +203,7 @@ func resourceLoadBalancerRuleRead(d *schema.ResourceData, meta interface{}) erro
 	}
 
 	lb := lbs[0]
+	readScope(d, lb)
 
 	d.Partial(true)
 
@@ -209,6 +222,32 @@ func resourceLoadBalancerRuleRead(d *schema.ResourceData, meta interface{}) erro
 	d.Set("public_ip_id", lb.PublicIpId.String())
 	d.SetPartial("public_ip_id")
 
+	privatePort, err := strconv.Atoi(lb.PrivatePort.String())
+	if err != nil {
+		return fmt.Errorf("Error convert string to int: %s", err)
+	}
+	d.Set("private_port", privatePort)
+	d.SetPartial("private_port")
+
+	publicPort, err := strconv.Atoi(lb.PublicPort.String())
+	if err != nil {
+		return fmt.Errorf("Error convert string to int: %s", err)
+	}
+	d.Set("public_port", publicPort)
+	d.SetPartial("public_port")
+
+	vms, err := config.client.ListLoadBalancerRuleInstances(
+		cloudstack.NewListLoadBalancerRuleInstancesParameter(d.Id()))
+	if err != nil {
+		return fmt.Errorf("Failed to list load balancer rule instances: %s", err)
+	}
+	vmIds := make([]string, len(vms))
+	for i, vm := range vms {
+		vmIds[i] = vm.Id.String()
+	}
+	d.Set("virtual_machine_ids", vmIds)
+	d.SetPartial("virtual_machine_ids")
+
 	d.Partial(false)
 
 	return nil
@@ -0,0 +1,51 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccLoadBalancerRule_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "loadbalancerrule"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccLoadBalancerRuleConfig("roundrobin", "")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "loadbalancerrule", "cs_load_balancer_rule.foo"),
+					resource.TestCheckResourceAttr("cs_load_balancer_rule.foo", "algorithm", "roundrobin"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccLoadBalancerRuleConfig("leastconn", `"${cs_virtual_machine.foo.id}"`)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_load_balancer_rule.foo", "algorithm", "leastconn"),
+					resource.TestCheckResourceAttr("cs_load_balancer_rule.foo", "virtual_machine_ids.#", "1"),
+				),
+			},
+			testAccImportStep(server, "cs_load_balancer_rule.foo"),
+		},
+	})
+}
+
+func testAccLoadBalancerRuleConfig(algorithm, vmIds string) string {
+	return testAccVirtualMachineConfig("vm01") + `
+resource "cs_ip_address" "foo" {
+}
+
+resource "cs_load_balancer_rule" "foo" {
+  name                = "lb01"
+  algorithm           = "` + algorithm + `"
+  private_port        = 80
+  public_port         = 80
+  public_ip_id        = "${cs_ip_address.foo.id}"
+  virtual_machine_ids = [` + vmIds + `]
+}
+`
+}
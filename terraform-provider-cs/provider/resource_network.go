@@ -10,37 +10,48 @@ import (
 
 func resourceNetwork() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceNetworkCreate,
-		Read:   resourceNetworkRead,
-		Update: resourceNetworkUpdate,
-		Delete: resourceNetworkDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceNetworkCreate),
+		Read:     resourceNetworkRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceNetworkUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceNetworkDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
 			},
 			"network_offering_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"network_offering_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"zone_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"zone_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"display_text": &schema.Schema{
 				Type:     schema.TypeString,
@@ -69,7 +80,22 @@ func resourceNetwork() *schema.Resource {
 				Computed: true,
 				ForceNew: true,
 			},
-		},
+			// vpc_id makes the network a tier of the VPC, whose cidr must
+			// contain gateway and netmask.
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			// acl_id is the network ACL list of a VPC tier, default_deny
+			// unless given.
+			"acl_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+		}),
 	}
 }
 
@@ -99,6 +125,14 @@ func resourceNetworkCreate(d *schema.ResourceData, meta interface{}) (err error)
 	if d.Get("netmask").(string) != "" {
 		param.Netmask.Set(d.Get("netmask"))
 	}
+	if d.Get("vpc_id").(string) != "" {
+		param.VpcId.Set(d.Get("vpc_id"))
+	}
+	if d.Get("acl_id").(string) != "" {
+		param.AclId.Set(d.Get("acl_id"))
+	}
+
+	getScope(d, meta).setParam(param)
 
 	nw, err := config.client.CreateNetwork(param)
 	if err != nil {
@@ -114,11 +148,13 @@ func resourceNetworkRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
 	param := cloudstack.NewListNetworksParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	nws, err := config.client.ListNetworks(param)
 
 	if err != nil {
 		param = cloudstack.NewListNetworksParameter()
+		getScope(d, meta).setListParam(param)
 		nws, err = config.client.ListNetworks(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list networks: %s", err)
@@ -136,6 +172,7 @@ func resourceNetworkRead(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	nw := nws[0]
+	readScope(d, nw)
 
 	d.Set("name", nw.Name.String())
 	d.Set("network_offering_id", nw.NetworkOfferingId.String())
@@ -147,6 +184,8 @@ func resourceNetworkRead(d *schema.ResourceData, meta interface{}) error {
 	d.Set("cidr", nw.Cidr.String())
 	d.Set("gateway", nw.Gateway.String())
 	d.Set("netmask", nw.Netmask.String())
+	d.Set("vpc_id", nw.VpcId.String())
+	d.Set("acl_id", nw.AclId.String())
 
 	return nil
 }
@@ -175,6 +214,14 @@ func resourceNetworkUpdate(d *schema.ResourceData, meta interface{}) error {
 		return fmt.Errorf("Error update network: %s", err)
 	}
 
+	if d.HasChange("acl_id") {
+		aclParam := cloudstack.NewReplaceNetworkACLListParameter(d.Get("acl_id").(string))
+		aclParam.NetworkId.Set(d.Id())
+		if _, err := config.client.ReplaceNetworkACLList(aclParam); err != nil {
+			return fmt.Errorf("Error replace network acl list: %s", err)
+		}
+	}
+
 	return resourceNetworkRead(d, meta)
 }
 
@@ -0,0 +1,324 @@
+package cloudstack
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceNetworkACLList() *schema.Resource {
+	rule := networkACLRuleSchema()
+	rule["id"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Computed: true,
+	}
+
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourceNetworkACLListCreate),
+		Read:     resourceNetworkACLListRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceNetworkACLListUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceNetworkACLListDelete),
+		Importer: &schema.ResourceImporter{State: importNetworkACLList},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"description": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// rule blocks are the rules of the list, identified by their
+			// rule_number. Unless managed is true, rules added outside of
+			// the resource are left alone.
+			"rule": &schema.Schema{
+				Type:     schema.TypeSet,
+				Set:      networkACLRuleHash,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: rule,
+				},
+			},
+			// managed makes the resource own every rule of the list, so
+			// that rules added outside of it show up in the plan and are
+			// deleted.
+			"managed": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+	}
+}
+
+func resourceNetworkACLListCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	name := d.Get("name").(string)
+	description := d.Get("description").(string)
+	if description == "" {
+		description = name
+	}
+
+	param := cloudstack.NewCreateNetworkACLListParameter(name, d.Get("vpc_id").(string))
+	param.Description.Set(description)
+
+	list, err := config.client.CreateNetworkACLList(param)
+	if err != nil {
+		return fmt.Errorf("Error create network acl list: %s", err)
+	}
+
+	d.SetId(list.Id.String())
+
+	if err := setNetworkACLRules(d, meta, nil); err != nil {
+		return err
+	}
+
+	return resourceNetworkACLListRead(d, meta)
+}
+
+func resourceNetworkACLListRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListNetworkACLListsParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	lists, err := config.client.ListNetworkACLLists(param)
+
+	if err != nil {
+		param = cloudstack.NewListNetworkACLListsParameter()
+		getScope(d, meta).setListParam(param)
+		lists, err = config.client.ListNetworkACLLists(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list network acl lists: %s", err)
+		}
+
+		fn := func(list interface{}) bool {
+			return list.(*cloudstack.NetworkACLList).Id.String() == d.Id()
+		}
+		lists = filter(lists, fn).([]*cloudstack.NetworkACLList)
+	}
+
+	if len(lists) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	list := lists[0]
+
+	d.Set("name", list.Name.String())
+	d.Set("description", list.Description.String())
+	d.Set("vpc_id", list.VpcId.String())
+
+	items, err := listNetworkACLItems(d, meta)
+	if err != nil {
+		return err
+	}
+
+	// Without managed only the rules known to the state are read.
+	known := make(map[int]bool)
+	for _, v := range d.Get("rule").(*schema.Set).List() {
+		known[v.(map[string]interface{})["rule_number"].(int)] = true
+	}
+
+	var rules []interface{}
+	for _, item := range items {
+		rule, err := networkACLRule(item)
+		if err != nil {
+			return err
+		}
+		if !d.Get("managed").(bool) && !known[rule["rule_number"].(int)] {
+			continue
+		}
+		rule["id"] = item.Id.String()
+		rules = append(rules, rule)
+	}
+	d.Set("rule", rules)
+
+	return nil
+}
+
+func resourceNetworkACLListUpdate(d *schema.ResourceData, meta interface{}) error {
+	if d.HasChange("rule") || d.HasChange("managed") {
+		old, _ := d.GetChange("rule")
+		if err := setNetworkACLRules(d, meta, old.(*schema.Set)); err != nil {
+			return err
+		}
+	}
+
+	return resourceNetworkACLListRead(d, meta)
+}
+
+func resourceNetworkACLListDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceNetworkACLListRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteNetworkACLListParameter(d.Id())
+	_, err := config.client.DeleteNetworkACLList(param)
+	if err != nil {
+		return fmt.Errorf("Error delete network acl list: %s", err)
+	}
+
+	return resourceNetworkACLListRead(d, meta)
+}
+
+// importNetworkACLList reads every rule of the imported list, since Read
+// only keeps those known to the state unless the list is managed.
+func importNetworkACLList(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	items, err := listNetworkACLItems(d, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []interface{}
+	for _, item := range items {
+		rule, err := networkACLRule(item)
+		if err != nil {
+			return nil, err
+		}
+		rule["id"] = item.Id.String()
+		rules = append(rules, rule)
+	}
+	d.Set("rule", rules)
+
+	return []*schema.ResourceData{d}, nil
+}
+
+// listNetworkACLItems returns the rules of the list of d.
+func listNetworkACLItems(d *schema.ResourceData, meta interface{}) ([]*cloudstack.NetworkACL, error) {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListNetworkACLsParameter()
+	getScope(d, meta).setListParam(param)
+	param.AclId.Set(d.Id())
+	items, err := config.client.ListNetworkACLs(param)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list network acl rules: %s", err)
+	}
+	return items, nil
+}
+
+// setNetworkACLRules changes the rules of the list of d from old to its rule
+// blocks as a whole: rules are matched by rule_number and deleted, updated
+// in place or created, and if any of that fails the changes made so far are
+// undone.
+func setNetworkACLRules(d *schema.ResourceData, meta interface{}, old *schema.Set) error {
+	config := meta.(*Config)
+
+	items, err := listNetworkACLItems(d, meta)
+	if err != nil {
+		return err
+	}
+	current := make(map[int]map[string]interface{})
+	ids := make(map[int]string)
+	for _, item := range items {
+		rule, err := networkACLRule(item)
+		if err != nil {
+			return err
+		}
+		number := rule["rule_number"].(int)
+		current[number] = rule
+		ids[number] = item.Id.String()
+	}
+
+	// Rules unknown to old are deleted only when the list is managed, but
+	// those with the number of a rule block are taken over.
+	owned := make(map[int]bool)
+	if old != nil {
+		for _, v := range old.List() {
+			owned[v.(map[string]interface{})["rule_number"].(int)] = true
+		}
+	}
+	if d.Get("managed").(bool) {
+		for number := range current {
+			owned[number] = true
+		}
+	}
+
+	desired := make(map[int]map[string]interface{})
+	for _, v := range d.Get("rule").(*schema.Set).List() {
+		rule := v.(map[string]interface{})
+		desired[rule["rule_number"].(int)] = rule
+	}
+
+	var undo []func() error
+	rollback := func(err error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				log.Printf("[WARN] Failed to undo a change of network acl list %s: %s", d.Id(), undoErr)
+			}
+		}
+		return err
+	}
+
+	for number, rule := range current {
+		if _, ok := desired[number]; ok || !owned[number] {
+			continue
+		}
+		param := cloudstack.NewDeleteNetworkACLParameter(ids[number])
+		if _, err := config.client.DeleteNetworkACL(param); err != nil {
+			return rollback(fmt.Errorf("Error delete network acl rule: %s", err))
+		}
+		rule := rule
+		undo = append(undo, func() error {
+			_, err := createNetworkACLItem(config, d.Id(), rule)
+			return err
+		})
+	}
+
+	for number, rule := range desired {
+		existing, ok := current[number]
+		if !ok || networkACLRuleHash(existing) == networkACLRuleHash(rule) {
+			continue
+		}
+		id := ids[number]
+		if err := updateNetworkACLItem(config, id, rule); err != nil {
+			return rollback(err)
+		}
+		undo = append(undo, func() error {
+			return updateNetworkACLItem(config, id, existing)
+		})
+	}
+
+	for number, rule := range desired {
+		if _, ok := current[number]; ok {
+			continue
+		}
+		item, err := createNetworkACLItem(config, d.Id(), rule)
+		if err != nil {
+			return rollback(err)
+		}
+		id := item.Id.String()
+		undo = append(undo, func() error {
+			_, err := config.client.DeleteNetworkACL(cloudstack.NewDeleteNetworkACLParameter(id))
+			return err
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccNetworkACLList_managed(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	var aclID string
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "networkacl"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkACLListConfig(true, 22, "")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "networkacllist", "cs_network_acl_list.foo"),
+					testAccCheckSameId("cs_network_acl_list.foo", &aclID),
+					resource.TestCheckResourceAttr("cs_network_acl_list.foo", "description", "web"),
+					resource.TestCheckResourceAttr("cs_network_acl_list.foo", "rule.#", "2"),
+					testAccCheckNetworkACLItems(server, "cs_network_acl_list.foo", map[int]string{10: "22", 20: ""}),
+				),
+			},
+			testAccImportStep(server, "cs_network_acl_list.foo", "managed"),
+			resource.TestStep{
+				// Rules added outside of a managed list are deleted.
+				PreConfig: func() {
+					testAccAddNetworkACLItem(server, aclID, 30)
+				},
+				Config: testAccConfig(server, testAccNetworkACLListConfig(true, 22, "")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_network_acl_list.foo", "rule.#", "2"),
+					testAccCheckNetworkACLItems(server, "cs_network_acl_list.foo", map[int]string{10: "22", 20: ""}),
+				),
+			},
+			resource.TestStep{
+				// An invalid rule undoes the update of rule 10.
+				Config: testAccConfig(server, testAccNetworkACLListConfig(true, 2222, `
+  rule {
+    rule_number = 40
+    protocol    = "tcp"
+    cidr_list   = ["10.0.0.0/33"]
+    start_port  = 80
+  }
+`)),
+				ExpectError: regexp.MustCompile(`Invalid cidr 10.0.0.0/33`),
+			},
+			resource.TestStep{
+				PreConfig: func() {
+					if n := server.Calls("updateNetworkACLItem"); n != 2 {
+						t.Errorf("updateNetworkACLItem was called %d times, expected 2", n)
+					}
+				},
+				Config: testAccConfig(server, testAccNetworkACLListConfig(true, 2222, "")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckSameId("cs_network_acl_list.foo", &aclID),
+					testAccCheckNetworkACLItems(server, "cs_network_acl_list.foo", map[int]string{10: "2222", 20: ""}),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNetworkACLList_unmanaged(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	var aclID string
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "networkacl"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkACLListConfig(false, 22, "")),
+				Check:  testAccCheckSameId("cs_network_acl_list.foo", &aclID),
+			},
+			resource.TestStep{
+				// Rules added outside of the list are left alone.
+				PreConfig: func() {
+					testAccAddNetworkACLItem(server, aclID, 30)
+				},
+				Config: testAccConfig(server, testAccNetworkACLListConfig(false, 22, "")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_network_acl_list.foo", "rule.#", "2"),
+					testAccCheckNetworkACLItems(server, "cs_network_acl_list.foo", map[int]string{10: "22", 20: "", 30: "8080"}),
+				),
+			},
+			resource.TestStep{
+				// An import reads every rule, as it doesn't know which are
+				// configured.
+				Config:        testAccConfig(server, ""),
+				ResourceName:  "cs_network_acl_list.foo",
+				ImportState:   true,
+				ImportStateId: aclID,
+				ImportStateCheck: func(s []*terraform.InstanceState) error {
+					if n := s[0].Attributes["rule.#"]; n != "3" {
+						return fmt.Errorf("Imported %s rules, expected 3", n)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func testAccNetworkACLListConfig(managed bool, sshPort int, rules string) string {
+	return fmt.Sprintf(`
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_network_acl_list" "foo" {
+  name    = "web"
+  vpc_id  = "${cs_vpc.foo.id}"
+  managed = %t
+
+  rule {
+    rule_number = 10
+    protocol    = "tcp"
+    cidr_list   = ["10.0.0.0/8"]
+    start_port  = %d
+  }
+
+  rule {
+    rule_number  = 20
+    action       = "deny"
+    protocol     = "icmp"
+    cidr_list    = ["0.0.0.0/0"]
+    traffic_type = "egress"
+  }
+%s}
+`, managed, sshPort, rules)
+}
+
+// testAccAddNetworkACLItem adds a rule with number to the ACL list aclID
+// behind the back of terraform.
+func testAccAddNetworkACLItem(server *cstest.Server, aclID string, number int) {
+	server.Add("networkacl", cstest.Object{
+		"aclid":       aclID,
+		"number":      number,
+		"action":      "Allow",
+		"protocol":    "tcp",
+		"cidrlist":    "0.0.0.0/0",
+		"startport":   "8080",
+		"endport":     "8080",
+		"traffictype": "Ingress",
+		"state":       "Active",
+	})
+}
+
+// testAccCheckNetworkACLItems checks that the ACL list of resource n has
+// exactly the rules with the numbers and start ports of items.
+func testAccCheckNetworkACLItems(server *cstest.Server, n string, items map[int]string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		aclID := s.RootModule().Resources[n].Primary.ID
+		found := make(map[int]string)
+		for _, item := range server.All("networkacl") {
+			if item["aclid"] == aclID {
+				port, _ := item["startport"].(string)
+				found[item["number"].(int)] = port
+			}
+		}
+		if fmt.Sprint(found) != fmt.Sprint(items) {
+			return fmt.Errorf("ACL list has rules %v, expected %v", found, items)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,321 @@
+package cloudstack
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+)
+
+func resourceNetworkACLRule() *schema.Resource {
+	s := networkACLRuleSchema()
+	s["acl_id"] = &schema.Schema{
+		Type:     schema.TypeString,
+		Required: true,
+		ForceNew: true,
+	}
+	// CloudStack numbers a rule after the last one of its list unless
+	// rule_number is given.
+	s["rule_number"].Required = false
+	s["rule_number"].Optional = true
+	s["rule_number"].Computed = true
+
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourceNetworkACLRuleCreate),
+		Read:     resourceNetworkACLRuleRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceNetworkACLRuleUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceNetworkACLRuleDelete),
+		Importer: &schema.ResourceImporter{State: schema.ImportStatePassthrough},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: s,
+	}
+}
+
+// networkACLRuleSchema returns the arguments of a network ACL rule, shared
+// by cs_network_acl_rule and the rule blocks of cs_network_acl_list.
+func networkACLRuleSchema() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"rule_number": &schema.Schema{
+			Type:     schema.TypeInt,
+			Required: true,
+		},
+		"action": &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "allow",
+			ValidateFunc: validation.StringInSlice([]string{"allow", "deny"}, false),
+		},
+		// protocol is tcp, udp, icmp, all or a protocol number.
+		"protocol": &schema.Schema{
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"cidr_list": &schema.Schema{
+			Type:     schema.TypeSet,
+			Required: true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+			Set: func(v interface{}) int {
+				return hashcode.String(v.(string))
+			},
+		},
+		"start_port": &schema.Schema{
+			Type:     schema.TypeInt,
+			Optional: true,
+		},
+		// end_port is start_port unless given.
+		"end_port": &schema.Schema{
+			Type:     schema.TypeInt,
+			Optional: true,
+			Computed: true,
+		},
+		// icmp_type and icmp_code of -1 match every type and code.
+		"icmp_type": &schema.Schema{
+			Type:     schema.TypeInt,
+			Optional: true,
+			Default:  -1,
+		},
+		"icmp_code": &schema.Schema{
+			Type:     schema.TypeInt,
+			Optional: true,
+			Default:  -1,
+		},
+		"traffic_type": &schema.Schema{
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "ingress",
+			ValidateFunc: validation.StringInSlice([]string{"ingress", "egress"}, false),
+		},
+	}
+}
+
+// networkACLRuleHash hashes the arguments of a rule block, leaving out its
+// computed id.
+func networkACLRuleHash(v interface{}) int {
+	m := v.(map[string]interface{})
+
+	cidrs := toStrings(m["cidr_list"].(*schema.Set).List())
+	sort.Strings(cidrs)
+
+	return hashcode.String(fmt.Sprintf("%d,%s,%s,%s,%d,%d,%d,%d,%s",
+		m["rule_number"].(int),
+		m["action"].(string),
+		strings.ToLower(m["protocol"].(string)),
+		strings.Join(cidrs, ","),
+		m["start_port"].(int),
+		endPort(m),
+		m["icmp_type"].(int),
+		m["icmp_code"].(int),
+		m["traffic_type"].(string),
+	))
+}
+
+// createNetworkACLItem adds rule, a map of the arguments of
+// networkACLRuleSchema, to the ACL list aclId.
+func createNetworkACLItem(config *Config, aclId string, rule map[string]interface{}) (*cloudstack.NetworkACL, error) {
+	param := cloudstack.NewCreateNetworkACLParameter(rule["protocol"].(string))
+	param.AclId.Set(aclId)
+	if number := rule["rule_number"].(int); number > 0 {
+		param.Number.Set(number)
+	}
+	param.Action.Set(rule["action"])
+	param.TrafficType.Set(rule["traffic_type"])
+	param.CidrList = toStrings(rule["cidr_list"].(*schema.Set).List())
+	if strings.ToLower(rule["protocol"].(string)) == "icmp" {
+		param.IcmpType.Set(rule["icmp_type"])
+		param.IcmpCode.Set(rule["icmp_code"])
+	} else if rule["start_port"].(int) != 0 {
+		param.StartPort.Set(rule["start_port"])
+		param.EndPort.Set(endPort(rule))
+	}
+
+	item, err := config.client.CreateNetworkACL(param)
+	if err != nil {
+		return nil, fmt.Errorf("Error create network acl rule: %s", err)
+	}
+	return item, nil
+}
+
+// updateNetworkACLItem changes the ACL item id in place to rule.
+func updateNetworkACLItem(config *Config, id string, rule map[string]interface{}) error {
+	param := cloudstack.NewUpdateNetworkACLItemParameter(id)
+	param.Number.Set(rule["rule_number"])
+	param.Protocol.Set(rule["protocol"])
+	param.Action.Set(rule["action"])
+	param.TrafficType.Set(rule["traffic_type"])
+	param.CidrList = toStrings(rule["cidr_list"].(*schema.Set).List())
+	if strings.ToLower(rule["protocol"].(string)) == "icmp" {
+		param.IcmpType.Set(rule["icmp_type"])
+		param.IcmpCode.Set(rule["icmp_code"])
+	} else if rule["start_port"].(int) != 0 {
+		param.StartPort.Set(rule["start_port"])
+		param.EndPort.Set(endPort(rule))
+	}
+
+	if _, err := config.client.UpdateNetworkACLItem(param); err != nil {
+		return fmt.Errorf("Error update network acl rule: %s", err)
+	}
+	return nil
+}
+
+// endPort returns the end port of rule, its start port when not given.
+func endPort(rule map[string]interface{}) int {
+	if rule["end_port"].(int) == 0 {
+		return rule["start_port"].(int)
+	}
+	return rule["end_port"].(int)
+}
+
+// networkACLRule returns the arguments of networkACLRuleSchema for item.
+func networkACLRule(item *cloudstack.NetworkACL) (map[string]interface{}, error) {
+	number, err := item.Number.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("Error convert to int: %s", err)
+	}
+
+	var cidrList []interface{}
+	for _, s := range strings.Split(item.CidrList.String(), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			cidrList = append(cidrList, s)
+		}
+	}
+
+	rule := map[string]interface{}{
+		"rule_number":  int(number),
+		"action":       strings.ToLower(item.Action.String()),
+		"protocol":     strings.ToLower(item.Protocol.String()),
+		"cidr_list":    schema.NewSet(schema.HashString, cidrList),
+		"start_port":   0,
+		"end_port":     0,
+		"icmp_type":    -1,
+		"icmp_code":    -1,
+		"traffic_type": strings.ToLower(item.TrafficType.String()),
+	}
+
+	for k, v := range map[string]cloudstack.NullString{"start_port": item.StartPort, "end_port": item.EndPort} {
+		if !v.IsNil() {
+			port, err := strconv.Atoi(v.String())
+			if err != nil {
+				return nil, fmt.Errorf("Error convert to int: %s", err)
+			}
+			rule[k] = port
+		}
+	}
+	for k, v := range map[string]cloudstack.NullNumber{"icmp_type": item.IcmpType, "icmp_code": item.IcmpCode} {
+		if !v.IsNil() {
+			n, err := v.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("Error convert to int: %s", err)
+			}
+			rule[k] = int(n)
+		}
+	}
+
+	return rule, nil
+}
+
+// resourceNetworkACLRuleArgs returns the rule arguments of d.
+func resourceNetworkACLRuleArgs(d *schema.ResourceData) map[string]interface{} {
+	rule := make(map[string]interface{})
+	for k := range networkACLRuleSchema() {
+		rule[k] = d.Get(k)
+	}
+	return rule
+}
+
+func resourceNetworkACLRuleCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	item, err := createNetworkACLItem(config, d.Get("acl_id").(string), resourceNetworkACLRuleArgs(d))
+	if err != nil {
+		return err
+	}
+
+	d.SetId(item.Id.String())
+
+	return resourceNetworkACLRuleRead(d, meta)
+}
+
+func resourceNetworkACLRuleRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListNetworkACLsParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	items, err := config.client.ListNetworkACLs(param)
+
+	if err != nil {
+		param = cloudstack.NewListNetworkACLsParameter()
+		getScope(d, meta).setListParam(param)
+		items, err = config.client.ListNetworkACLs(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list network acl rules: %s", err)
+		}
+
+		fn := func(item interface{}) bool {
+			return item.(*cloudstack.NetworkACL).Id.String() == d.Id()
+		}
+		items = filter(items, fn).([]*cloudstack.NetworkACL)
+	}
+
+	if len(items) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	item := items[0]
+	rule, err := networkACLRule(item)
+	if err != nil {
+		return err
+	}
+
+	d.Set("acl_id", item.AclId.String())
+	for k, v := range rule {
+		d.Set(k, v)
+	}
+
+	return nil
+}
+
+func resourceNetworkACLRuleUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := updateNetworkACLItem(config, d.Id(), resourceNetworkACLRuleArgs(d)); err != nil {
+		return err
+	}
+
+	return resourceNetworkACLRuleRead(d, meta)
+}
+
+func resourceNetworkACLRuleDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceNetworkACLRuleRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteNetworkACLParameter(d.Id())
+	_, err := config.client.DeleteNetworkACL(param)
+	if err != nil {
+		return fmt.Errorf("Error delete network acl rule: %s", err)
+	}
+
+	return resourceNetworkACLRuleRead(d, meta)
+}
@@ -0,0 +1,92 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccNetworkACLRule_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	var id string
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "networkacl"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkACLRuleConfig(`
+  protocol   = "tcp"
+  cidr_list  = ["0.0.0.0/0"]
+  start_port = 80
+`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "networkacl", "cs_network_acl_rule.foo"),
+					testAccCheckSameId("cs_network_acl_rule.foo", &id),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "rule_number", "1"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "action", "allow"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "end_port", "80"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "traffic_type", "ingress"),
+					resource.TestCheckResourceAttrPair("cs_network.foo", "acl_id", "cs_network_acl_list.foo", "id"),
+				),
+			},
+			testAccImportStep(server, "cs_network_acl_rule.foo"),
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkACLRuleConfig(`
+  rule_number  = 5
+  action       = "deny"
+  protocol     = "icmp"
+  cidr_list    = ["10.0.0.0/8", "192.168.0.0/16"]
+  icmp_type    = 8
+  icmp_code    = 0
+  traffic_type = "egress"
+`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckSameId("cs_network_acl_rule.foo", &id),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "rule_number", "5"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "action", "deny"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "cidr_list.#", "2"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "icmp_type", "8"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "start_port", "0"),
+					resource.TestCheckResourceAttr("cs_network_acl_rule.foo", "traffic_type", "egress"),
+				),
+			},
+		},
+	})
+}
+
+func testAccNetworkACLRuleConfig(args string) string {
+	return testAccNetworkACLVPCConfig + `
+resource "cs_network_acl_rule" "foo" {
+  acl_id = "${cs_network_acl_list.foo.id}"
+` + args + `}
+`
+}
+
+// testAccNetworkACLVPCConfig is a VPC with a tier using the ACL list foo.
+const testAccNetworkACLVPCConfig = `
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_network_acl_list" "foo" {
+  name   = "web"
+  vpc_id = "${cs_vpc.foo.id}"
+}
+
+resource "cs_network" "foo" {
+  name                  = "web"
+  display_text          = "web"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingForVpcNetworks"
+  vpc_id                = "${cs_vpc.foo.id}"
+  gateway               = "10.1.1.1"
+  netmask               = "255.255.255.0"
+  acl_id                = "${cs_network_acl_list.foo.id}"
+}
+`
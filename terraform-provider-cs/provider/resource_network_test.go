@@ -0,0 +1,130 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccNetwork_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "network"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkConfig("net01")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "network", "cs_network.foo"),
+					resource.TestCheckResourceAttr("cs_network.foo", "name", "net01"),
+					resource.TestCheckResourceAttr("cs_network.foo", "cidr", "10.10.0.0/24"),
+					resource.TestCheckResourceAttr("cs_network.foo", "zone_name", "zone1"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkConfig("net02")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_network.foo", "name", "net02"),
+				),
+			},
+			testAccImportStep(server, "cs_network.foo"),
+		},
+	})
+}
+
+func testAccNetworkConfig(name string) string {
+	return `
+resource "cs_network" "foo" {
+  name                  = "` + name + `"
+  display_text          = "test network"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+  gateway               = "10.10.0.1"
+  netmask               = "255.255.255.0"
+}
+`
+}
+
+func TestAccNetwork_namesInIds(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	config := func(zone string) string {
+		return `
+resource "cs_network" "foo" {
+  name                = "net01"
+  display_text        = "test network"
+  zone_id             = "` + zone + `"
+  network_offering_id = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+}
+`
+	}
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "network"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				// The names stay in the configuration without a diff.
+				Config: testAccConfig(server, config("zone1")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "network", "cs_network.foo"),
+					resource.TestCheckResourceAttr("cs_network.foo", "zone_id", server.All("zone")[0]["id"].(string)),
+					resource.TestCheckResourceAttr("cs_network.foo", "zone_name", "zone1"),
+				),
+			},
+			resource.TestStep{
+				Config:      testAccConfig(server, config("zone9")),
+				ExpectError: regexp.MustCompile(`No zone named zone9 is found`),
+			},
+		},
+	})
+}
+
+func TestAccNetwork_account(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	domain := server.All("domain")[0]
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "network"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccNetworkConfig("net01")+`
+resource "cs_network" "bar" {
+  name                  = "net01"
+  display_text          = "test network"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+  account               = "user1"
+  domain_id             = "`+domain["id"].(string)+`"
+}
+
+resource "cs_virtual_machine" "foo" {
+  zone_name             = "zone1"
+  service_offering_name = "small"
+  template_name         = "CentOS 7"
+  network_names         = ["net01"]
+  account               = "user1"
+  domain_id             = "`+domain["id"].(string)+`"
+  expunge               = true
+  depends_on            = ["cs_network.bar"]
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "network", "cs_network.bar"),
+					resource.TestCheckResourceAttr("cs_network.foo", "account", "admin"),
+					resource.TestCheckResourceAttr("cs_network.bar", "account", "user1"),
+					resource.TestCheckResourceAttrPair(
+						"cs_virtual_machine.foo", "nic.0.network_id", "cs_network.bar", "id"),
+				),
+			},
+		},
+	})
+}
@@ -13,11 +13,17 @@ import (
 
 func resourcePortForwardingRule() *schema.Resource {
 	return &schema.Resource{
-		Create: resourcePortForwardingRuleCreate,
-		Read:   resourcePortForwardingRuleRead,
-		Delete: resourcePortForwardingRuleDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourcePortForwardingRuleCreate),
+		Read:     resourcePortForwardingRuleRead,
+		Delete:   withTimeout(schema.TimeoutDelete, resourcePortForwardingRuleDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"ip_address_id": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -68,12 +74,13 @@ func resourcePortForwardingRule() *schema.Resource {
 				ForceNew: true,
 			},
 			"open_firewall": &schema.Schema{
-				Type:     schema.TypeBool,
-				Default:  false,
-				Optional: true,
-				ForceNew: true,
+				Type:             schema.TypeBool,
+				Default:          false,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressAfterImport,
 			},
-		},
+		}),
 	}
 }
 
@@ -119,11 +126,13 @@ func resourcePortForwardingRuleRead(d *schema.ResourceData, meta interface{}) er
 	config := meta.(*Config)
 
 	param := cloudstack.NewListPortForwardingRulesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	pfRules, err := config.client.ListPortForwardingRules(param)
 
 	if err != nil {
 		param = cloudstack.NewListPortForwardingRulesParameter()
+		getScope(d, meta).setListParam(param)
 		pfRules, err = config.client.ListPortForwardingRules(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list portforwarding rule: %s", err)
@@ -141,6 +150,23 @@ func resourcePortForwardingRuleRead(d *schema.ResourceData, meta interface{}) er
 	}
 
 	pfRule := pfRules[0]
+	readScope(d, pfRule)
+
+	d.Set("ip_address_id", pfRule.IpAddressId.String())
+	d.Set("protocol", pfRule.Protocol.String())
+	d.Set("virtual_machine_id", pfRule.VirtualMachineId.String())
+
+	privatePort, err := strconv.Atoi(pfRule.PrivatePort.String())
+	if err != nil {
+		return fmt.Errorf("Error convert string to int: %s", err)
+	}
+	d.Set("private_port", privatePort)
+
+	publicPort, err := strconv.Atoi(pfRule.PublicPort.String())
+	if err != nil {
+		return fmt.Errorf("Error convert string to int: %s", err)
+	}
+	d.Set("public_port", publicPort)
 
 	var cidrList []interface{}
 	for _, s := range strings.Split(pfRule.CidrList.String(), ",") {
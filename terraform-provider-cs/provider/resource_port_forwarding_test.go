@@ -0,0 +1,42 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccPortForwardingRule_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "portforwardingrule"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVirtualMachineConfig("vm01")+`
+resource "cs_ip_address" "foo" {
+}
+
+resource "cs_port_forwarding_rule" "foo" {
+  ip_address_id      = "${cs_ip_address.foo.id}"
+  protocol           = "tcp"
+  private_port       = 22
+  public_port        = 2222
+  virtual_machine_id = "${cs_virtual_machine.foo.id}"
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "portforwardingrule", "cs_port_forwarding_rule.foo"),
+					resource.TestCheckResourceAttr("cs_port_forwarding_rule.foo", "private_port", "22"),
+					resource.TestCheckResourceAttr("cs_port_forwarding_rule.foo", "public_port", "2222"),
+					resource.TestCheckResourceAttr("cs_port_forwarding_rule.foo", "private_end_port", "22"),
+					resource.TestCheckResourceAttr("cs_port_forwarding_rule.foo", "public_end_port", "2222"),
+				),
+			},
+			testAccImportStep(server, "cs_port_forwarding_rule.foo", "open_firewall"),
+		},
+	})
+}
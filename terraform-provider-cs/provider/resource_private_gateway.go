@@ -0,0 +1,220 @@
+package cloudstack
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourcePrivateGateway() *schema.Resource {
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourcePrivateGatewayCreate),
+		Read:     resourcePrivateGatewayRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourcePrivateGatewayUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourcePrivateGatewayDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: withScope(map[string]*schema.Schema{
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// CloudStack takes a VLAN with or without the vlan:// scheme
+			// and returns it as a URI.
+			"vlan": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+				DiffSuppressFunc: func(k, old, new string, d *schema.ResourceData) bool {
+					return trimVlanScheme(old) == trimVlanScheme(new)
+				},
+			},
+			"ip_address": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"gateway": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"netmask": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// The physical network defaults to the one of the zone of the
+			// VPC.
+			"physical_network_id": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"physical_network_name": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"source_nat": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+				ForceNew: true,
+			},
+			// acl_id is the network ACL list of the gateway, default_deny
+			// unless given.
+			"acl_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func resourcePrivateGatewayCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewCreatePrivateGatewayParameter(
+		d.Get("gateway").(string), d.Get("ip_address").(string),
+		d.Get("netmask").(string), d.Get("vpc_id").(string))
+	param.Vlan.Set(d.Get("vlan"))
+	param.SourceNatSupported.Set(d.Get("source_nat"))
+
+	_, hasId := d.GetOk("physical_network_id")
+	_, hasName := d.GetOk("physical_network_name")
+	if hasId || hasName {
+		physicalNetworkId, err := getResourceId(d, meta, "physical_network")
+		if err != nil {
+			return err
+		}
+		param.PhysicalNetworkId.Set(physicalNetworkId)
+	}
+	if d.Get("acl_id").(string) != "" {
+		param.AclId.Set(d.Get("acl_id"))
+	}
+
+	privateGateway, err := config.client.CreatePrivateGateway(param)
+	if err != nil {
+		return fmt.Errorf("Error create private gateway: %s", err)
+	}
+
+	d.SetId(privateGateway.Id.String())
+
+	return resourcePrivateGatewayRead(d, meta)
+}
+
+func resourcePrivateGatewayRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListPrivateGatewaysParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	privateGateways, err := config.client.ListPrivateGateways(param)
+
+	if err != nil {
+		param = cloudstack.NewListPrivateGatewaysParameter()
+		getScope(d, meta).setListParam(param)
+		privateGateways, err = config.client.ListPrivateGateways(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list private gateways: %s", err)
+		}
+
+		fn := func(privateGateway interface{}) bool {
+			return privateGateway.(*cloudstack.PrivateGateway).Id.String() == d.Id()
+		}
+		privateGateways = filter(privateGateways, fn).([]*cloudstack.PrivateGateway)
+	}
+
+	if len(privateGateways) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	privateGateway := privateGateways[0]
+	readScope(d, privateGateway)
+
+	d.Set("vpc_id", privateGateway.VpcId.String())
+	d.Set("vlan", trimVlanScheme(privateGateway.Vlan.String()))
+	d.Set("ip_address", privateGateway.IpAddress.String())
+	d.Set("gateway", privateGateway.Gateway.String())
+	d.Set("netmask", privateGateway.Netmask.String())
+	d.Set("physical_network_id", privateGateway.PhysicalNetworkId.String())
+
+	// Without an id listPhysicalNetworks lists them all.
+	if privateGateway.PhysicalNetworkId.String() != "" {
+		pnParam := cloudstack.NewListPhysicalNetworksParameter()
+		pnParam.Id.Set(privateGateway.PhysicalNetworkId.String())
+		physicalNetworks, err := config.client.ListPhysicalNetworks(pnParam)
+		if err != nil {
+			return fmt.Errorf("Failed to list physical networks: %s", err)
+		}
+		if len(physicalNetworks) > 0 {
+			d.Set("physical_network_name", physicalNetworks[0].Name.String())
+		}
+	}
+
+	d.Set("source_nat", privateGateway.SourceNatSupported.Bool())
+	d.Set("acl_id", privateGateway.AclId.String())
+	d.Set("state", privateGateway.State.String())
+
+	return nil
+}
+
+func resourcePrivateGatewayUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if d.HasChange("acl_id") {
+		param := cloudstack.NewReplaceNetworkACLListParameter(d.Get("acl_id").(string))
+		param.GatewayId.Set(d.Id())
+		if _, err := config.client.ReplaceNetworkACLList(param); err != nil {
+			return fmt.Errorf("Error replace network acl list: %s", err)
+		}
+	}
+
+	return resourcePrivateGatewayRead(d, meta)
+}
+
+func resourcePrivateGatewayDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourcePrivateGatewayRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeletePrivateGatewayParameter(d.Id())
+	_, err := config.client.DeletePrivateGateway(param)
+	if err != nil {
+		return fmt.Errorf("Error delete private gateway: %s", err)
+	}
+
+	return resourcePrivateGatewayRead(d, meta)
+}
+
+// trimVlanScheme returns the VLAN vlan without the vlan:// scheme.
+func trimVlanScheme(vlan string) string {
+	return strings.TrimPrefix(vlan, "vlan://")
+}
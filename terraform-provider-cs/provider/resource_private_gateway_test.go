@@ -0,0 +1,159 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccPrivateGateway_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	var id string
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "privategateway"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccPrivateGatewayConfig("")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "privategateway", "cs_private_gateway.foo"),
+					testAccCheckSameId("cs_private_gateway.foo", &id),
+					resource.TestCheckResourceAttrSet("cs_private_gateway.foo", "physical_network_id"),
+					resource.TestCheckResourceAttr("cs_private_gateway.foo", "vlan", "100"),
+					resource.TestCheckResourceAttr("cs_private_gateway.foo", "source_nat", "true"),
+					resource.TestCheckResourceAttr("cs_private_gateway.foo", "state", "Ready"),
+					testAccCheckPrivateGatewayACL(server, "cs_private_gateway.foo", "default_deny"),
+				),
+			},
+			testAccImportStep(server, "cs_private_gateway.foo"),
+			resource.TestStep{
+				Config: testAccConfig(server, testAccPrivateGatewayConfig(`
+  acl_id = "${cs_network_acl_list.foo.id}"`)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckSameId("cs_private_gateway.foo", &id),
+					resource.TestCheckResourceAttrPair("cs_private_gateway.foo", "acl_id", "cs_network_acl_list.foo", "id"),
+					testAccCheckPrivateGatewayACL(server, "cs_private_gateway.foo", "onprem"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, strings.Replace(testAccPrivateGatewayConfig(`
+  acl_id = "${cs_network_acl_list.foo.id}"`), `"vlan://100"`, `"100"`, 1)),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccPrivateGateway_noPhysicalNetwork(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	privateGateway := server.Add("privategateway", cstest.Object{
+		"ipaddress": "172.16.0.2",
+		"gateway":   "172.16.0.1",
+		"netmask":   "255.255.255.252",
+		"vlan":      "vlan://100",
+		"account":   "admin",
+		"domainid":  server.All("domain")[0]["id"],
+		"state":     "Ready",
+	})
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:        testAccConfig(server, ""),
+				ResourceName:  "cs_private_gateway.foo",
+				ImportState:   true,
+				ImportStateId: privateGateway["id"].(string),
+				ImportStateCheck: func(states []*terraform.InstanceState) error {
+					attrs := states[0].Attributes
+					if attrs["vlan"] != "100" || attrs["physical_network_name"] != "" {
+						return fmt.Errorf("Imported vlan %q and physical network %q, expected 100 and none",
+							attrs["vlan"], attrs["physical_network_name"])
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func testAccPrivateGatewayConfig(args string) string {
+	return `
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_network_acl_list" "foo" {
+  name   = "onprem"
+  vpc_id = "${cs_vpc.foo.id}"
+}
+
+resource "cs_private_gateway" "foo" {
+  vpc_id                = "${cs_vpc.foo.id}"
+  vlan                  = "vlan://100"
+  ip_address            = "172.16.0.2"
+  gateway               = "172.16.0.1"
+  netmask               = "255.255.255.252"
+  physical_network_name = "physnet1"
+  source_nat            = true` + args + `
+}
+`
+}
+
+// testAccCheckPrivateGatewayACL checks that the private gateway of resource
+// n uses the ACL list named acl.
+func testAccCheckPrivateGatewayACL(server *cstest.Server, n, acl string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		privateGateway := server.Get("privategateway", s.RootModule().Resources[n].Primary.ID)
+		if privateGateway == nil {
+			return fmt.Errorf("Private gateway of %s not found", n)
+		}
+		list := server.Get("networkacllist", fmt.Sprint(privateGateway["aclid"]))
+		if list == nil || list["name"] != acl {
+			return fmt.Errorf("Private gateway uses ACL list %v, expected %s", privateGateway["aclid"], acl)
+		}
+		return nil
+	}
+}
+
+func TestAccPrivateGateway_otherSubnet(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_private_gateway" "foo" {
+  vpc_id     = "${cs_vpc.foo.id}"
+  vlan       = "vlan://100"
+  ip_address = "172.16.0.6"
+  gateway    = "172.16.0.1"
+  netmask    = "255.255.255.252"
+}
+`),
+				ExpectError: regexp.MustCompile(`The gateway 172.16.0.1 and ip address 172.16.0.6 are not in the same subnet`),
+			},
+		},
+	})
+}
@@ -11,12 +11,19 @@ import (
 
 func resourceSecurityGroup() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceSecurityGroupCreate,
-		Read:   resourceSecurityGroupRead,
-		Update: resourceSecurityGroupUpdate,
-		Delete: resourceSecurityGroupDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceSecurityGroupCreate),
+		Read:     resourceSecurityGroupRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceSecurityGroupUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceSecurityGroupDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -106,7 +113,7 @@ func resourceSecurityGroup() *schema.Resource {
 					},
 				},
 			},
-		},
+		}),
 	}
 }
 
@@ -129,8 +136,14 @@ func securityGroupRuleHash(v interface{}) int {
 func resourceSecurityGroupCreate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
+	if err := config.requireSecurityGroups("cs_security_group"); err != nil {
+		return err
+	}
+
 	param := cloudstack.NewCreateSecurityGroupParameter(d.Get("name").(string))
 
+	getScope(d, meta).setParam(param)
+
 	sg, err := config.client.CreateSecurityGroup(param)
 	if err != nil {
 		return fmt.Errorf("Error create security group: %s", err)
@@ -151,11 +164,13 @@ func resourceSecurityGroupRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
 	param := cloudstack.NewListSecurityGroupsParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	sgs, err := config.client.ListSecurityGroups(param)
 
 	if err != nil {
 		param = cloudstack.NewListSecurityGroupsParameter()
+		getScope(d, meta).setListParam(param)
 		sgs, err = config.client.ListSecurityGroups(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list firewall rule: %s", err)
@@ -173,6 +188,9 @@ func resourceSecurityGroupRead(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	sg := sgs[0]
+	readScope(d, sg)
+
+	d.Set("name", sg.Name.String())
 
 	egressRule := make([]map[string]interface{}, len(sg.EgressRule))
 	for i, rule := range sg.EgressRule {
@@ -0,0 +1,66 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccSecurityGroup_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "securitygroup"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_security_group" "foo" {
+  name = "sg01"
+
+  ingress_rule {
+    protocol   = "tcp"
+    cidr       = "0.0.0.0/0"
+    start_port = 22
+    end_port   = 22
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "securitygroup", "cs_security_group.foo"),
+					resource.TestCheckResourceAttr("cs_security_group.foo", "name", "sg01"),
+					resource.TestCheckResourceAttr("cs_security_group.foo", "ingress_rule.#", "1"),
+					resource.TestCheckResourceAttr("cs_security_group.foo", "egress_rule.#", "0"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_security_group" "foo" {
+  name = "sg01"
+
+  ingress_rule {
+    protocol   = "tcp"
+    cidr       = "0.0.0.0/0"
+    start_port = 443
+    end_port   = 443
+  }
+
+  egress_rule {
+    protocol  = "icmp"
+    cidr      = "10.0.0.0/8"
+    icmp_type = -1
+    icmp_code = -1
+  }
+}
+`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_security_group.foo", "ingress_rule.#", "1"),
+					resource.TestCheckResourceAttr("cs_security_group.foo", "egress_rule.#", "1"),
+				),
+			},
+			testAccImportStep(server, "cs_security_group.foo"),
+		},
+	})
+}
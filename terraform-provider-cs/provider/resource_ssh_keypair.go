@@ -0,0 +1,151 @@
+package cloudstack
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceSSHKeyPair() *schema.Resource {
+	return &schema.Resource{
+		Create:   resourceSSHKeyPairCreate,
+		Read:     resourceSSHKeyPairRead,
+		Delete:   resourceSSHKeyPairDelete,
+		Importer: resourceImporter(),
+
+		Schema: withScope(map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			// public_key is registered when given; otherwise CloudStack
+			// generates the key pair and returns private_key once.
+			"public_key": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameFingerprint,
+			},
+			"private_key": &schema.Schema{
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+			"fingerprint": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func resourceSSHKeyPairCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	name := d.Get("name").(string)
+	s := getScope(d, meta)
+
+	if publicKey, ok := d.GetOk("public_key"); ok {
+		param := cloudstack.NewRegisterSSHKeyPairParameter(name, publicKey.(string))
+		s.setParam(param)
+		if _, err := config.client.RegisterSSHKeyPair(param); err != nil {
+			return fmt.Errorf("Error register ssh keypair: %s", err)
+		}
+	} else {
+		param := cloudstack.NewCreateSSHKeyPairParameter(name)
+		s.setParam(param)
+		keyPair, err := config.client.CreateSSHKeyPair(param)
+		if err != nil {
+			return fmt.Errorf("Error create ssh keypair: %s", err)
+		}
+		d.Set("private_key", keyPair.PrivateKey.String())
+	}
+
+	// Key pairs have no id but a name unique in their account.
+	d.SetId(name)
+
+	return resourceSSHKeyPairRead(d, meta)
+}
+
+func resourceSSHKeyPairRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListSSHKeyPairsParameter()
+	getScope(d, meta).setListParam(param)
+	param.Name.Set(d.Id())
+	keyPairs, err := config.client.ListSSHKeyPairs(param)
+	if err != nil {
+		return fmt.Errorf("Failed to list ssh keypairs: %s", err)
+	}
+
+	if len(keyPairs) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	keyPair := keyPairs[0]
+	readScope(d, keyPair)
+
+	d.Set("name", keyPair.Name.String())
+	d.Set("fingerprint", keyPair.Fingerprint.String())
+
+	return nil
+}
+
+func resourceSSHKeyPairDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceSSHKeyPairRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteSSHKeyPairParameter(d.Id())
+	getScope(d, meta).setParam(param)
+	_, err := config.client.DeleteSSHKeyPair(param)
+	if err != nil {
+		return fmt.Errorf("Error delete ssh keypair: %s", err)
+	}
+
+	return resourceSSHKeyPairRead(d, meta)
+}
+
+// suppressSameFingerprint suppresses the diff of a public key which
+// CloudStack doesn't return, as after an import, when it has the
+// fingerprint of the key pair.
+func suppressSameFingerprint(k, old, new string, d *schema.ResourceData) bool {
+	if old != "" || d.Id() == "" {
+		return false
+	}
+	fingerprint, err := sshFingerprint(new)
+	return err == nil && fingerprint == d.Get("fingerprint").(string)
+}
+
+// sshFingerprint returns the MD5 fingerprint of an OpenSSH public key, the
+// format CloudStack uses.
+func sshFingerprint(publicKey string) (string, error) {
+	fields := strings.Fields(publicKey)
+	if len(fields) < 2 {
+		return "", fmt.Errorf("Invalid public key: %q", publicKey)
+	}
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("Invalid public key: %s", err)
+	}
+
+	sum := md5.Sum(blob)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(hex, ":"), nil
+}
@@ -0,0 +1,119 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceStaticRoute() *schema.Resource {
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourceStaticRouteCreate),
+		Read:     resourceStaticRouteRead,
+		Delete:   withTimeout(schema.TimeoutDelete, resourceStaticRouteDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: withScope(map[string]*schema.Schema{
+			// cidr must be outside of the cidr of the VPC.
+			"cidr": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"gateway_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vpc_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func resourceStaticRouteCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewCreateStaticRouteParameter(
+		d.Get("cidr").(string), d.Get("gateway_id").(string))
+
+	route, err := config.client.CreateStaticRoute(param)
+	if err != nil {
+		return fmt.Errorf("Error create static route: %s", err)
+	}
+
+	d.SetId(route.Id.String())
+
+	return resourceStaticRouteRead(d, meta)
+}
+
+func resourceStaticRouteRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListStaticRoutesParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	routes, err := config.client.ListStaticRoutes(param)
+
+	if err != nil {
+		param = cloudstack.NewListStaticRoutesParameter()
+		getScope(d, meta).setListParam(param)
+		routes, err = config.client.ListStaticRoutes(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list static routes: %s", err)
+		}
+
+		fn := func(route interface{}) bool {
+			return route.(*cloudstack.StaticRoute).Id.String() == d.Id()
+		}
+		routes = filter(routes, fn).([]*cloudstack.StaticRoute)
+	}
+
+	if len(routes) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	route := routes[0]
+	readScope(d, route)
+
+	d.Set("cidr", route.Cidr.String())
+	d.Set("gateway_id", route.GatewayId.String())
+	d.Set("vpc_id", route.VpcId.String())
+	d.Set("state", route.State.String())
+
+	return nil
+}
+
+func resourceStaticRouteDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceStaticRouteRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteStaticRouteParameter(d.Id())
+	_, err := config.client.DeleteStaticRoute(param)
+	if err != nil {
+		return fmt.Errorf("Error delete static route: %s", err)
+	}
+
+	return resourceStaticRouteRead(d, meta)
+}
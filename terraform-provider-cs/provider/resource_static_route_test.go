@@ -0,0 +1,69 @@
+package cloudstack
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccStaticRoute_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "staticroute"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccStaticRouteConfig("192.168.0.0/16")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "staticroute", "cs_static_route.foo"),
+					resource.TestCheckResourceAttrPair("cs_static_route.foo", "vpc_id", "cs_vpc.foo", "id"),
+					resource.TestCheckResourceAttr("cs_static_route.foo", "state", "Active"),
+				),
+			},
+			testAccImportStep(server, "cs_static_route.foo"),
+		},
+	})
+}
+
+func TestAccStaticRoute_insideVPC(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config:      testAccConfig(server, testAccStaticRouteConfig("10.1.8.0/24")),
+				ExpectError: regexp.MustCompile(`CIDR should be outside of VPC cidr 10.1.0.0/16`),
+			},
+		},
+	})
+}
+
+func testAccStaticRouteConfig(cidr string) string {
+	return `
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_private_gateway" "foo" {
+  vpc_id     = "${cs_vpc.foo.id}"
+  vlan       = "vlan://100"
+  ip_address = "172.16.0.2"
+  gateway    = "172.16.0.1"
+  netmask    = "255.255.255.252"
+}
+
+resource "cs_static_route" "foo" {
+  cidr       = "` + cidr + `"
+  gateway_id = "${cs_private_gateway.foo.id}"
+}
+`
+}
@@ -2,52 +2,82 @@ package cloudstack
 
 import (
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/atsaki/golang-cloudstack-library"
 
 	"github.com/hashicorp/terraform/helper/hashcode"
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
 )
 
 func resourceVirtualMachine() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceVirtualMachineCreate,
-		Read:   resourceVirtualMachineRead,
-		Update: resourceVirtualMachineUpdate,
-		Delete: resourceVirtualMachineDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceVirtualMachineCreate),
+		Read:     resourceVirtualMachineRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceVirtualMachineUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceVirtualMachineDelete),
+		Importer: &schema.ResourceImporter{State: importVirtualMachine},
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"zone_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"zone_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"service_offering_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"service_offering_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"template_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"template_name": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			// template_filter selects the templates template_name is
+			// looked up in.
+			"template_filter": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				ValidateFunc:     validation.StringInSlice(templateFilters, false),
+				DiffSuppressFunc: suppressAfterImport,
+			},
+			"hypervisor": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
 				Computed: true,
@@ -96,6 +126,7 @@ func resourceVirtualMachine() *schema.Resource {
 			"security_groups": &schema.Schema{
 				Type:     schema.TypeSet,
 				Optional: true,
+				Computed: true,
 				ForceNew: true,
 				Elem: &schema.Schema{
 					Type: schema.TypeString,
@@ -104,6 +135,30 @@ func resourceVirtualMachine() *schema.Resource {
 					return hashcode.String(v.(string))
 				},
 			},
+			"affinity_group_ids": &schema.Schema{
+				Type:             schema.TypeSet,
+				Optional:         true,
+				ConflictsWith:    []string{"affinity_group_names"},
+				DiffSuppressFunc: suppressOtherAffinityGroups,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Set: func(v interface{}) int {
+					return hashcode.String(v.(string))
+				},
+			},
+			"affinity_group_names": &schema.Schema{
+				Type:             schema.TypeSet,
+				Optional:         true,
+				ConflictsWith:    []string{"affinity_group_ids"},
+				DiffSuppressFunc: suppressOtherAffinityGroups,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+				Set: func(v interface{}) int {
+					return hashcode.String(v.(string))
+				},
+			},
 			"user_data": &schema.Schema{
 				Type:     schema.TypeString,
 				Optional: true,
@@ -114,52 +169,57 @@ func resourceVirtualMachine() *schema.Resource {
 				Optional: true,
 				Default:  false,
 			},
-			"nic": &schema.Schema{
-				Type:     schema.TypeList,
-				Computed: true,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"id": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"gateway": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"ip_address": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"is_default": &schema.Schema{
-							Type:     schema.TypeBool,
-							Computed: true,
-						},
-						"mac_address": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"netmask": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"network_id": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"network_name": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"traffic_type": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-						"type": &schema.Schema{
-							Type:     schema.TypeString,
-							Computed: true,
-						},
-					},
+			"nic": nicSchema(),
+		}),
+	}
+}
+
+// nicSchema is the computed nic block of virtual machines.
+func nicSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Computed: true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"id": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"gateway": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"ip_address": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"is_default": &schema.Schema{
+					Type:     schema.TypeBool,
+					Computed: true,
+				},
+				"mac_address": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"netmask": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"network_id": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"network_name": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"traffic_type": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
+				},
+				"type": &schema.Schema{
+					Type:     schema.TypeString,
+					Computed: true,
 				},
 			},
 		},
@@ -197,7 +257,7 @@ func resourceVirtualMachineCreate(d *schema.ResourceData, meta interface{}) erro
 	} else if len(tmpNetworkNames) > 0 {
 		networkIds = make([]string, len(tmpNetworkNames))
 		for i, networkName := range tmpNetworkNames {
-			networkId, err := nameToID(config.client, "network", networkName.(string))
+			networkId, err := nameToID(config.client, "network", networkName.(string), lookup{scope: getScope(d, meta)})
 			if err != nil {
 				return err
 			}
@@ -220,6 +280,10 @@ func resourceVirtualMachineCreate(d *schema.ResourceData, meta interface{}) erro
 		param.DisplayName.Set(d.Get("display_name"))
 	}
 
+	if d.Get("hypervisor").(string) != "" {
+		param.Hypervisor.Set(d.Get("hypervisor"))
+	}
+
 	if d.Get("user_data").(string) != "" {
 		param.UserData.Set(d.Get("user_data"))
 	}
@@ -230,12 +294,26 @@ func resourceVirtualMachineCreate(d *schema.ResourceData, meta interface{}) erro
 
 	if d.Get("security_groups") != nil {
 		sgNames := d.Get("security_groups").(*schema.Set).List()
+		if len(sgNames) > 0 {
+			if err := config.requireSecurityGroups("security_groups"); err != nil {
+				return err
+			}
+		}
 		param.SecurityGroupNames = make([]string, len(sgNames))
 		for i, sgName := range sgNames {
 			param.SecurityGroupNames[i] = sgName.(string)
 		}
 	}
 
+	if ids := toStrings(d.Get("affinity_group_ids").(*schema.Set).List()); len(ids) > 0 {
+		param.AffinityGroupIds = ids
+	}
+	if names := toStrings(d.Get("affinity_group_names").(*schema.Set).List()); len(names) > 0 {
+		param.AffinityGroupNames = names
+	}
+
+	getScope(d, meta).setParam(param)
+
 	vm, err := config.client.DeployVirtualMachine(param)
 	if err != nil {
 		return fmt.Errorf("Error deploy virtualmachine: %s", err)
@@ -250,10 +328,12 @@ func resourceVirtualMachineRead(d *schema.ResourceData, meta interface{}) error
 	config := meta.(*Config)
 
 	param := cloudstack.NewListVirtualMachinesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	vms, err := config.client.ListVirtualMachines(param)
 	if err != nil {
 		param = cloudstack.NewListVirtualMachinesParameter()
+		getScope(d, meta).setListParam(param)
 		vms, err = config.client.ListVirtualMachines(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list virtualmachines: %s", err)
@@ -271,6 +351,7 @@ func resourceVirtualMachineRead(d *schema.ResourceData, meta interface{}) error
 	}
 
 	vm := vms[0]
+	readScope(d, vm)
 
 	d.Set("zone_id", vm.ZoneId.String())
 	d.Set("zone_name", vm.ZoneName.String())
@@ -278,12 +359,109 @@ func resourceVirtualMachineRead(d *schema.ResourceData, meta interface{}) error
 	d.Set("service_offering_name", vm.ServiceOfferingName.String())
 	d.Set("template_id", vm.TemplateId.String())
 	d.Set("template_name", vm.TemplateName.String())
+	d.Set("hypervisor", vm.Hypervisor.String())
 	d.Set("name", vm.Name.String())
 	d.Set("display_name", vm.DisplayName.String())
+	d.Set("key_pair", vm.KeyPair.String())
 
-	nics := make([]map[string]interface{}, len(vm.Nic))
 	networkIds := make([]string, len(vm.Nic))
 	networkNames := make([]string, len(vm.Nic))
+	for i, nic := range vm.Nic {
+		networkIds[i] = nic.NetworkId.String()
+		networkNames[i] = nic.NetworkName.String()
+	}
+	d.Set("nic", flattenNics(vm))
+	d.Set("network_ids", networkIds)
+	d.Set("network_names", networkNames)
+
+	sgNames := make([]string, len(vm.SecurityGroup))
+	for i, sg := range vm.SecurityGroup {
+		sgNames[i] = sg.Name.String()
+	}
+	d.Set("security_groups", sgNames)
+
+	// Only the arguments in use are set, so that removing every group from
+	// one is a change. Imported virtual machines have both.
+	_, byName := d.GetOk("affinity_group_names")
+	_, byId := d.GetOk("affinity_group_ids")
+	setAffinityGroups(d, vm, byName, byId || !byName)
+
+	// getVirtualMachineUserData was added in 4.4; before it user_data is
+	// left as configured.
+	if err := config.requireVersion("Reading user_data", "4.4"); err != nil {
+		log.Printf("[DEBUG] %s", err)
+		return nil
+	}
+	userData, err := config.client.GetVirtualMachineUserData(
+		cloudstack.NewGetVirtualMachineUserDataParameter(d.Id()))
+	if err != nil {
+		return fmt.Errorf("Failed to get user data of virtualmachine: %s", err)
+	}
+	d.Set("user_data", userData.UserData.String())
+
+	return nil
+}
+
+// setAffinityGroups sets affinity_group_names and affinity_group_ids to the
+// groups of vm.
+func setAffinityGroups(d *schema.ResourceData, vm *cloudstack.VirtualMachine, names, ids bool) {
+	groupIds := make([]string, len(vm.AffinityGroup))
+	groupNames := make([]string, len(vm.AffinityGroup))
+	for i, group := range vm.AffinityGroup {
+		groupIds[i] = group.Id.String()
+		groupNames[i] = group.Name.String()
+	}
+	if names {
+		d.Set("affinity_group_names", groupNames)
+	}
+	if ids {
+		d.Set("affinity_group_ids", groupIds)
+	}
+}
+
+// importVirtualMachine imports a virtual machine with both its affinity
+// group ids and names, as the configuration may use either.
+func importVirtualMachine(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	config := meta.(*Config)
+
+	if _, err := importState(d, meta); err != nil {
+		return nil, err
+	}
+
+	param := cloudstack.NewListVirtualMachinesParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	vms, err := config.client.ListVirtualMachines(param)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list virtualmachines: %s", err)
+	}
+	if len(vms) > 0 && len(vms[0].AffinityGroup) > 0 {
+		setAffinityGroups(d, vms[0], true, true)
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// suppressOtherAffinityGroups suppresses removing affinity_group_ids when
+// the configuration names the groups by affinity_group_names instead, and
+// the other way around, which happens after an import set both. To leave
+// every group then, set the argument in use to [].
+func suppressOtherAffinityGroups(k, old, new string, d *schema.ResourceData) bool {
+	key, other := "affinity_group_ids", "affinity_group_names"
+	if strings.HasPrefix(k, other) {
+		key, other = other, key
+	}
+	if o, _ := d.GetChange(other); o.(*schema.Set).Len() == 0 {
+		return false
+	}
+	// Without the argument in the configuration its value is the one in
+	// the state, although the diff removes it.
+	o, n := d.GetChange(key)
+	return o.(*schema.Set).Equal(n)
+}
+
+// flattenNics returns the nic block of vm.
+func flattenNics(vm *cloudstack.VirtualMachine) []map[string]interface{} {
+	nics := make([]map[string]interface{}, len(vm.Nic))
 	for i, nic := range vm.Nic {
 		m := make(map[string]interface{})
 		m["id"] = nic.Id.String()
@@ -297,26 +475,19 @@ func resourceVirtualMachineRead(d *schema.ResourceData, meta interface{}) error
 		m["traffic_type"] = nic.TrafficType.String()
 		m["type"] = nic.Type.String()
 		nics[i] = m
-
-		networkIds[i] = nic.NetworkId.String()
-		networkNames[i] = nic.NetworkName.String()
-	}
-	d.Set("nic", nics)
-	d.Set("network_ids", networkIds)
-	d.Set("network_names", networkNames)
-
-	sgNames := make([]string, len(vm.SecurityGroup))
-	for i, sg := range vm.SecurityGroup {
-		sgNames[i] = sg.Name.String()
 	}
-	d.Set("security_groups", nics)
-
-	return nil
+	return nics
 }
 
 func resourceVirtualMachineUpdate(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
+	if d.HasChange("affinity_group_ids") || d.HasChange("affinity_group_names") {
+		if err := updateAffinityGroups(d, meta); err != nil {
+			return err
+		}
+	}
+
 	param := cloudstack.NewUpdateVirtualMachineParameter(d.Id())
 
 	if d.HasChange("display_name") {
@@ -330,6 +501,118 @@ func resourceVirtualMachineUpdate(d *schema.ResourceData, meta interface{}) erro
 	return resourceVirtualMachineRead(d, meta)
 }
 
+// updateAffinityGroups replaces the affinity groups of a virtual machine.
+// CloudStack only does so while it is stopped, so a running one is stopped
+// and started again, after the groups are known to exist.
+func updateAffinityGroups(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	groupIds, err := affinityGroupIds(d, meta)
+	if err != nil {
+		return err
+	}
+
+	listParam := cloudstack.NewListVirtualMachinesParameter()
+	getScope(d, meta).setListParam(listParam)
+	listParam.Id.Set(d.Id())
+	vms, err := config.client.ListVirtualMachines(listParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list virtualmachines: %s", err)
+	}
+	if len(vms) > 0 && sameAffinityGroups(vms[0], groupIds) {
+		return nil
+	}
+	running := len(vms) > 0 && vms[0].State.String() == "Running"
+
+	if running {
+		_, err := config.client.StopVirtualMachine(cloudstack.NewStopVirtualMachineParameter(d.Id()))
+		if err != nil {
+			return fmt.Errorf("Error stop virtualmachine: %s", err)
+		}
+	}
+
+	param := cloudstack.NewUpdateVMAffinityGroupParameter(d.Id())
+	param.AffinityGroupIds = groupIds
+	_, updateErr := config.client.UpdateVMAffinityGroup(param)
+
+	// Start the virtual machine again even if the update failed.
+	if running {
+		_, err := config.client.StartVirtualMachine(cloudstack.NewStartVirtualMachineParameter(d.Id()))
+		if err != nil && updateErr != nil {
+			return fmt.Errorf("Error update affinity groups of virtualmachine: %s, "+
+				"and error start it again: %s", updateErr, err)
+		}
+		if err != nil {
+			return fmt.Errorf("Error start virtualmachine: %s", err)
+		}
+	}
+
+	if updateErr != nil {
+		return fmt.Errorf("Error update affinity groups of virtualmachine: %s", updateErr)
+	}
+	return nil
+}
+
+// sameAffinityGroups reports whether vm is in exactly the groups ids.
+func sameAffinityGroups(vm *cloudstack.VirtualMachine, ids []string) bool {
+	if len(vm.AffinityGroup) != len(ids) {
+		return false
+	}
+	want := make(map[string]bool)
+	for _, id := range ids {
+		want[id] = true
+	}
+	for _, group := range vm.AffinityGroup {
+		if !want[group.Id.String()] {
+			return false
+		}
+	}
+	return true
+}
+
+// affinityGroupIds returns the ids of the affinity groups given by the
+// changed one of affinity_group_names and affinity_group_ids, none if it
+// is emptied.
+func affinityGroupIds(d *schema.ResourceData, meta interface{}) ([]string, error) {
+	config := meta.(*Config)
+	l := lookup{scope: getScope(d, meta)}
+
+	names := toStrings(d.Get("affinity_group_names").(*schema.Set).List())
+	ids := toStrings(d.Get("affinity_group_ids").(*schema.Set).List())
+	if !d.HasChange("affinity_group_names") {
+		names = nil
+	}
+	if !d.HasChange("affinity_group_ids") {
+		ids = nil
+	}
+
+	if len(names) > 0 {
+		groupIds := make([]string, len(names))
+		for i, name := range names {
+			id, err := nameToID(config.client, "affinity_group", name, l)
+			if err != nil {
+				return nil, err
+			}
+			groupIds[i] = id
+		}
+		return groupIds, nil
+	}
+
+	for _, id := range ids {
+		param := cloudstack.NewListAffinityGroupsParameter()
+		param.Id.Set(id)
+		l.setListParam(param)
+		groups, err := config.client.ListAffinityGroups(param)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to list affinity groups: %s", err)
+		}
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("No affinity_group with id %s is found", id)
+		}
+	}
+	return ids, nil
+}
+
 func resourceVirtualMachineDelete(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
@@ -0,0 +1,167 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccVirtualMachine_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "virtualmachine"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVirtualMachineConfig("web01")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "virtualmachine", "cs_virtual_machine.foo"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "name", "vm01"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "hypervisor", "KVM"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "display_name", "web01"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "service_offering_name", "small"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "nic.#", "1"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "nic.0.ip_address", "10.1.1.2"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "nic.0.is_default", "true"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVirtualMachineConfig("web02")),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "display_name", "web02"),
+				),
+			},
+			testAccImportStep(server, "cs_virtual_machine.foo", "expunge"),
+		},
+	})
+}
+
+func testAccVirtualMachineConfig(displayName string) string {
+	return `
+resource "cs_network" "foo" {
+  name                  = "net01"
+  display_text          = "net01"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingWithSourceNatService"
+}
+
+resource "cs_virtual_machine" "foo" {
+  name                  = "vm01"
+  display_name          = "` + displayName + `"
+  zone_name             = "zone1"
+  service_offering_name = "small"
+  template_name         = "CentOS 7"
+  network_ids           = ["${cs_network.foo.id}"]
+  expunge               = true
+}
+`
+}
+
+func TestAccVirtualMachine_createTimeout(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	config := strings.Replace(testAccVirtualMachineConfig("web01"), "expunge               = true", `expunge               = true
+
+  timeouts {
+    create = "100ms"
+  }`, 1)
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "virtualmachine"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				PreConfig: func() {
+					server.HoldJobs("deployVirtualMachine")
+				},
+				Config:      testAccConfig(server, config),
+				ExpectError: regexp.MustCompile(`Timeout after 100ms while waiting for async job`),
+			},
+			resource.TestStep{
+				// The VM whose deployment timed out is tainted and replaced
+				// rather than left behind.
+				PreConfig: func() {
+					server.ReleaseJobs("deployVirtualMachine")
+				},
+				Config: testAccConfig(server, config),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "virtualmachine", "cs_virtual_machine.foo"),
+					func(*terraform.State) error {
+						if n := len(server.All("virtualmachine")); n != 1 {
+							return fmt.Errorf("%d virtual machines exist, expected 1", n)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func TestAccVirtualMachine_project(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	project := server.All("project")[0]
+	args := fmt.Sprintf(`  project_id = "%s"`, project["id"])
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "virtualmachine"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccProviderConfig(server, args, testAccVirtualMachineConfig("web01")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "virtualmachine", "cs_virtual_machine.foo"),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "project_id", project["id"].(string)),
+					resource.TestCheckResourceAttr("cs_network.foo", "project_id", project["id"].(string)),
+					resource.TestCheckResourceAttr("cs_virtual_machine.foo", "account", ""),
+				),
+			},
+			resource.TestStep{
+				// Without the projectid in the list calls the resources
+				// would vanish from the state and be created again.
+				Config:   testAccProviderConfig(server, args, testAccVirtualMachineConfig("web01")),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccVirtualMachine_importProject(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	project := server.All("project")[0]
+	args := fmt.Sprintf(`  project_id = "%s"`, project["id"])
+
+	for _, prefix := range []string{project["name"].(string), project["id"].(string)} {
+		resource.UnitTest(t, resource.TestCase{
+			Providers:    testAccProviders,
+			CheckDestroy: testAccCheckDestroy(server, "virtualmachine"),
+			Steps: []resource.TestStep{
+				resource.TestStep{
+					Config: testAccProviderConfig(server, args, testAccVirtualMachineConfig("web01")),
+				},
+				resource.TestStep{
+					// The provider has no project_id, so the project
+					// comes from the <project>/<id> form.
+					Config:                  testAccConfig(server, ""),
+					ResourceName:            "cs_virtual_machine.foo",
+					ImportState:             true,
+					ImportStateIdPrefix:     prefix + "/",
+					ImportStateVerify:       true,
+					ImportStateVerifyIgnore: []string{"expunge"},
+				},
+			},
+		})
+	}
+}
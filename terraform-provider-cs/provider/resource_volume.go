@@ -10,26 +10,35 @@ import (
 
 func resourceVolume() *schema.Resource {
 	return &schema.Resource{
-		Create: resourceVolumeCreate,
-		Read:   resourceVolumeRead,
-		Update: resourceVolumeUpdate,
-		Delete: resourceVolumeDelete,
+		Create:   withTimeout(schema.TimeoutCreate, resourceVolumeCreate),
+		Read:     resourceVolumeRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceVolumeUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceVolumeDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
 
-		Schema: map[string]*schema.Schema{
+		Schema: withScope(map[string]*schema.Schema{
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
 				ForceNew: true,
 			},
 			"disk_offering_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"disk_offering_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			// size unit is GB
 			"size": &schema.Schema{
@@ -47,18 +56,20 @@ func resourceVolume() *schema.Resource {
 				Computed: true,
 			},
 			"zone_id": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
 			"zone_name": &schema.Schema{
-				Type:     schema.TypeString,
-				Optional: true,
-				Computed: true,
-				ForceNew: true,
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
 			},
-		},
+		}),
 	}
 }
 
@@ -87,6 +98,8 @@ func resourceVolumeCreate(d *schema.ResourceData, meta interface{}) error {
 		param.Size.Set(d.Get("size").(int))
 	}
 
+	getScope(d, meta).setParam(param)
+
 	volume, err := config.client.CreateVolume(param)
 	if err != nil {
 		return fmt.Errorf("Error create volume: %s", err)
@@ -101,11 +114,13 @@ func resourceVolumeRead(d *schema.ResourceData, meta interface{}) error {
 	config := meta.(*Config)
 
 	param := cloudstack.NewListVolumesParameter()
+	getScope(d, meta).setListParam(param)
 	param.Id.Set(d.Id())
 	volumes, err := config.client.ListVolumes(param)
 
 	if err != nil {
 		param = cloudstack.NewListVolumesParameter()
+		getScope(d, meta).setListParam(param)
 		volumes, err = config.client.ListVolumes(param)
 		if err != nil {
 			return fmt.Errorf("Failed to list volumes: %s", err)
@@ -123,6 +138,7 @@ func resourceVolumeRead(d *schema.ResourceData, meta interface{}) error {
 	}
 
 	volume := volumes[0]
+	readScope(d, volume)
 
 	d.Set("name", volume.Name.String())
 	d.Set("disk_offering_id", volume.DiskOfferingId.String())
@@ -187,6 +203,10 @@ func resourceVolumeUpdate(d *schema.ResourceData, meta interface{}) error {
 		(diskOfferingId != "" && diskOfferingId != d.Get("disk_offering_id").(string)) ||
 		(diskOfferingName != "" && diskOfferingName != d.Get("disk_offering_name").(string)) {
 
+		if err := config.requireVersion("Resizing a volume", "4.2"); err != nil {
+			return err
+		}
+
 		param := cloudstack.NewResizeVolumeParameter(d.Id())
 
 		if (diskOfferingId != "" && diskOfferingId != d.Get("disk_offering_id").(string)) ||
@@ -0,0 +1,53 @@
+package cloudstack
+
+import (
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+)
+
+func TestAccVolume_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "volume"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVolumeConfig(false)),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "volume", "cs_volume.foo"),
+					resource.TestCheckResourceAttr("cs_volume.foo", "size", "5"),
+					resource.TestCheckResourceAttr("cs_volume.foo", "is_attached", "false"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVolumeConfig(true)),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("cs_volume.foo", "is_attached", "true"),
+					resource.TestCheckResourceAttrPair(
+						"cs_volume.foo", "virtual_machine_id", "cs_virtual_machine.foo", "id"),
+				),
+			},
+			testAccImportStep(server, "cs_volume.foo"),
+		},
+	})
+}
+
+func testAccVolumeConfig(attached bool) string {
+	config := testAccVirtualMachineConfig("vm01") + `
+resource "cs_volume" "foo" {
+  name               = "data01"
+  zone_name          = "zone1"
+  disk_offering_name = "small"
+`
+	if attached {
+		config += `
+  is_attached        = true
+  virtual_machine_id = "${cs_virtual_machine.foo.id}"
+`
+	}
+	return config + "}\n"
+}
@@ -0,0 +1,283 @@
+package cloudstack
+
+import (
+	"fmt"
+
+	"github.com/atsaki/golang-cloudstack-library"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceVPC() *schema.Resource {
+	return &schema.Resource{
+		Create:   withTimeout(schema.TimeoutCreate, resourceVPCCreate),
+		Read:     resourceVPCRead,
+		Update:   withTimeout(schema.TimeoutUpdate, resourceVPCUpdate),
+		Delete:   withTimeout(schema.TimeoutDelete, resourceVPCDelete),
+		Importer: resourceImporter(),
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(defaultTimeout),
+			Update: schema.DefaultTimeout(defaultTimeout),
+			Delete: schema.DefaultTimeout(defaultTimeout),
+		},
+
+		Schema: withScope(map[string]*schema.Schema{
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"display_text": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+			},
+			"cidr": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"vpc_offering_id": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"vpc_offering_name": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"zone_id": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"zone_name": &schema.Schema{
+				Type:             schema.TypeString,
+				Optional:         true,
+				Computed:         true,
+				ForceNew:         true,
+				DiffSuppressFunc: suppressSameObject,
+			},
+			"network_domain": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			// redundant_router makes the VPC redundant by restarting it.
+			// CloudStack can't make a redundant VPC non-redundant again.
+			"redundant_router": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Computed: true,
+			},
+			// restart restarts the VPC whenever its value changes, cleaning
+			// up its routers when cleanup is true.
+			"restart": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"cleanup": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"state": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"source_nat_ip": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"source_nat_ip_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		}),
+	}
+}
+
+func resourceVPCCreate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	zoneId, err := getResourceId(d, meta, "zone")
+	if err != nil {
+		return err
+	}
+
+	vpcOfferingId, err := getResourceId(d, meta, "vpc_offering")
+	if err != nil {
+		return err
+	}
+
+	name := d.Get("name").(string)
+	displayText := d.Get("display_text").(string)
+	if displayText == "" {
+		displayText = name
+	}
+
+	param := cloudstack.NewCreateVPCParameter(
+		d.Get("cidr").(string), displayText, name, vpcOfferingId, zoneId)
+	if d.Get("network_domain").(string) != "" {
+		param.NetworkDomain.Set(d.Get("network_domain"))
+	}
+
+	getScope(d, meta).setParam(param)
+
+	vpc, err := config.client.CreateVPC(param)
+	if err != nil {
+		return fmt.Errorf("Error create vpc: %s", err)
+	}
+
+	d.SetId(vpc.Id.String())
+
+	// The offering decides whether a new VPC is redundant.
+	if v, ok := d.GetOkExists("redundant_router"); ok && v.(bool) != vpc.RedundantVpcRouter.Bool() {
+		if !v.(bool) {
+			return fmt.Errorf("VPC offering %s has redundant routers", vpc.VpcOfferingName.String())
+		}
+		if err := restartVPC(d, meta); err != nil {
+			return err
+		}
+	}
+
+	return resourceVPCRead(d, meta)
+}
+
+func resourceVPCRead(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewListVPCsParameter()
+	getScope(d, meta).setListParam(param)
+	param.Id.Set(d.Id())
+	vpcs, err := config.client.ListVPCs(param)
+
+	if err != nil {
+		param = cloudstack.NewListVPCsParameter()
+		getScope(d, meta).setListParam(param)
+		vpcs, err = config.client.ListVPCs(param)
+		if err != nil {
+			return fmt.Errorf("Failed to list vpcs: %s", err)
+		}
+
+		fn := func(vpc interface{}) bool {
+			return vpc.(*cloudstack.VPC).Id.String() == d.Id()
+		}
+		vpcs = filter(vpcs, fn).([]*cloudstack.VPC)
+	}
+
+	if len(vpcs) == 0 {
+		d.SetId("")
+		return nil
+	}
+
+	vpc := vpcs[0]
+	readScope(d, vpc)
+
+	d.Set("name", vpc.Name.String())
+	d.Set("display_text", vpc.DisplayText.String())
+	d.Set("cidr", vpc.Cidr.String())
+	d.Set("vpc_offering_id", vpc.VpcOfferingId.String())
+	d.Set("vpc_offering_name", vpc.VpcOfferingName.String())
+	d.Set("zone_id", vpc.ZoneId.String())
+	d.Set("zone_name", vpc.ZoneName.String())
+	d.Set("network_domain", vpc.NetworkDomain.String())
+	d.Set("redundant_router", vpc.RedundantVpcRouter.Bool())
+	d.Set("state", vpc.State.String())
+
+	ipParam := cloudstack.NewListPublicIpAddressesParameter()
+	getScope(d, meta).setListParam(ipParam)
+	ipParam.VpcId.Set(d.Id())
+	ipParam.IsSourceNat.Set(true)
+	ips, err := config.client.ListPublicIpAddresses(ipParam)
+	if err != nil {
+		return fmt.Errorf("Failed to list ipaddress: %s", err)
+	}
+	if len(ips) > 0 {
+		d.Set("source_nat_ip", ips[0].IpAddress.String())
+		d.Set("source_nat_ip_id", ips[0].Id.String())
+	} else {
+		d.Set("source_nat_ip", "")
+		d.Set("source_nat_ip_id", "")
+	}
+
+	return nil
+}
+
+func resourceVPCUpdate(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if d.HasChange("name") || d.HasChange("display_text") {
+		param := cloudstack.NewUpdateVPCParameter(d.Id())
+		param.Name.Set(d.Get("name"))
+		if d.Get("display_text").(string) != "" {
+			param.DisplayText.Set(d.Get("display_text"))
+		}
+		if _, err := config.client.UpdateVPC(param); err != nil {
+			return fmt.Errorf("Error update vpc: %s", err)
+		}
+	}
+
+	if d.HasChange("redundant_router") {
+		old, _ := d.GetChange("redundant_router")
+		if old.(bool) && !d.Get("redundant_router").(bool) {
+			return fmt.Errorf("VPC %s can't be made non-redundant", d.Get("name").(string))
+		}
+	}
+
+	if d.HasChange("restart") || d.HasChange("redundant_router") {
+		if err := restartVPC(d, meta); err != nil {
+			return err
+		}
+	}
+
+	return resourceVPCRead(d, meta)
+}
+
+// restartVPC restarts the VPC, with cleanup when asked to and making it
+// redundant when redundant_router has been set.
+func restartVPC(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	param := cloudstack.NewRestartVPCParameter(d.Id())
+	param.Cleanup.Set(d.Get("cleanup").(bool))
+	if d.HasChange("redundant_router") && d.Get("redundant_router").(bool) {
+		if err := config.requireVersion("Redundant VPC routers", "4.6"); err != nil {
+			return err
+		}
+		param.MakeRedundant.Set(true)
+	}
+
+	if _, err := config.client.RestartVPC(param); err != nil {
+		return fmt.Errorf("Error restart vpc: %s", err)
+	}
+	return nil
+}
+
+func resourceVPCDelete(d *schema.ResourceData, meta interface{}) error {
+	config := meta.(*Config)
+
+	if err := resourceVPCRead(d, meta); err != nil {
+		return err
+	}
+
+	if d.Id() == "" {
+		return nil
+	}
+
+	param := cloudstack.NewDeleteVPCParameter(d.Id())
+	_, err := config.client.DeleteVPC(param)
+	if err != nil {
+		return fmt.Errorf("Error delete vpc: %s", err)
+	}
+
+	return resourceVPCRead(d, meta)
+}
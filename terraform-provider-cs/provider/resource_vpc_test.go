@@ -0,0 +1,152 @@
+package cloudstack
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccVPC_basic(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	var id string
+	resource.UnitTest(t, resource.TestCase{
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckDestroy(server, "vpc"),
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVPCConfig(server, "vpc01", "", "default_deny")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckExists(server, "vpc", "cs_vpc.foo"),
+					testAccCheckSameId("cs_vpc.foo", &id),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "display_text", "vpc01"),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "state", "Enabled"),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "redundant_router", "false"),
+					resource.TestCheckResourceAttrSet("cs_vpc.foo", "source_nat_ip"),
+					resource.TestCheckResourceAttrSet("cs_vpc.foo", "source_nat_ip_id"),
+					resource.TestCheckResourceAttrPair("cs_network.foo", "vpc_id", "cs_vpc.foo", "id"),
+					testAccCheckNetworkACL(server, "cs_network.foo", "default_deny"),
+				),
+			},
+			testAccImportStep(server, "cs_vpc.foo", "restart", "cleanup"),
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVPCConfig(server, "vpc02", `
+  display_text     = "web tier"
+  redundant_router = true
+  restart          = "1"
+  cleanup          = true
+`, "default_allow")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckSameId("cs_vpc.foo", &id),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "name", "vpc02"),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "display_text", "web tier"),
+					resource.TestCheckResourceAttr("cs_vpc.foo", "redundant_router", "true"),
+					testAccCheckVPCRestarts(server, 1),
+					testAccCheckNetworkACL(server, "cs_network.foo", "default_allow"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccConfig(server, testAccVPCConfig(server, "vpc02", `
+  display_text     = "web tier"
+  redundant_router = true
+  restart          = "2"
+  cleanup          = true
+`, "default_allow")),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckSameId("cs_vpc.foo", &id),
+					testAccCheckVPCRestarts(server, 2),
+				),
+			},
+		},
+	})
+}
+
+func testAccVPCConfig(server *cstest.Server, name, args, acl string) string {
+	var aclID string
+	for _, obj := range server.All("networkacllist") {
+		if obj["name"] == acl {
+			aclID = obj["id"].(string)
+		}
+	}
+	return fmt.Sprintf(`
+resource "cs_vpc" "foo" {
+  name              = "%s"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+%s}
+
+resource "cs_network" "foo" {
+  name                  = "web"
+  display_text          = "web"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingForVpcNetworks"
+  vpc_id                = "${cs_vpc.foo.id}"
+  gateway               = "10.1.1.1"
+  netmask               = "255.255.255.0"
+  acl_id                = "%s"
+}
+`, name, args, aclID)
+}
+
+func testAccCheckVPCRestarts(server *cstest.Server, n int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		if calls := server.Calls("restartVPC"); calls != n {
+			return fmt.Errorf("restartVPC was called %d times, expected %d", calls, n)
+		}
+		return nil
+	}
+}
+
+// testAccCheckNetworkACL checks that the network of resource n uses the
+// ACL list named acl.
+func testAccCheckNetworkACL(server *cstest.Server, n, acl string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		network := server.Get("network", s.RootModule().Resources[n].Primary.ID)
+		if network == nil {
+			return fmt.Errorf("Network of %s not found", n)
+		}
+		list := server.Get("networkacllist", fmt.Sprint(network["aclid"]))
+		if list == nil || list["name"] != acl {
+			return fmt.Errorf("Network uses ACL list %v, expected %s", network["aclid"], acl)
+		}
+		return nil
+	}
+}
+
+func TestAccVPC_tierOutsideCidr(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	resource.UnitTest(t, resource.TestCase{
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccConfig(server, `
+resource "cs_vpc" "foo" {
+  name              = "vpc01"
+  cidr              = "10.1.0.0/16"
+  vpc_offering_name = "Default VPC offering"
+  zone_name         = "zone1"
+}
+
+resource "cs_network" "foo" {
+  name                  = "web"
+  display_text          = "web"
+  zone_name             = "zone1"
+  network_offering_name = "DefaultIsolatedNetworkOfferingForVpcNetworks"
+  vpc_id                = "${cs_vpc.foo.id}"
+  gateway               = "10.2.1.1"
+  netmask               = "255.255.255.0"
+}
+`),
+				ExpectError: regexp.MustCompile(`Network cidr 10.2.1.0/24 is not within VPC cidr 10.1.0.0/16`),
+			},
+		},
+	})
+}
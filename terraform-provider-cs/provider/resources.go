@@ -1 +1,61 @@
 package cloudstack
+
+import (
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// resourceImporter imports resources by their id. Those of a project are
+// listed only with its id, so they are imported as <project>/<id>, where the
+// project is given by id or name.
+func resourceImporter() *schema.ResourceImporter {
+	return &schema.ResourceImporter{
+		State: importState,
+	}
+}
+
+func importState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	config := meta.(*Config)
+
+	if parts := strings.SplitN(d.Id(), "/", 2); len(parts) == 2 {
+		if err := config.requireProjects("Importing a resource of a project"); err != nil {
+			return nil, err
+		}
+		projectId := parts[0]
+		if !isUUID(projectId) {
+			var err error
+			projectId, err = nameToID(config.client, "project", projectId, lookup{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		d.Set("project_id", projectId)
+		d.SetId(parts[1])
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// suppressAfterImport suppresses the diff of an argument which only matters
+// when the resource is created and so is missing from the state of imported
+// resources.
+func suppressAfterImport(k, old, new string, d *schema.ResourceData) bool {
+	return d.Id() != "" && old == ""
+}
+
+// suppressSameObject suppresses the diff of an _id or _name argument given
+// the name or the id of the object already in the state, e.g. zone_id =
+// "zone1" for the zone which zone_name shows is named zone1, since either
+// argument accepts both.
+func suppressSameObject(k, old, new string, d *schema.ResourceData) bool {
+	if old == "" || new == "" {
+		return false
+	}
+	switch {
+	case strings.HasSuffix(k, "_id") && !isUUID(new):
+		return d.Get(strings.TrimSuffix(k, "_id")+"_name").(string) == new
+	case strings.HasSuffix(k, "_name") && isUUID(new):
+		return d.Get(strings.TrimSuffix(k, "_name")+"_id").(string) == new
+	}
+	return false
+}
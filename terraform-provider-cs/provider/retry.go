@@ -0,0 +1,169 @@
+package cloudstack
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultMaxRetries = 3
+
+var (
+	retryBaseDelay = 1 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// retryableStatus lists the HTTP status codes worth retrying. CloudStack
+// reports its own error codes as the HTTP status, e.g. 534 when a resource
+// is unavailable for the moment. The internal error 530 is left out as it
+// rarely goes away by itself.
+var retryableStatus = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+	statusTooManyRequests:         true,
+	534:                           true, // resource unavailable
+}
+
+// statusTooManyRequests is returned by CloudStack when api.throttling
+// rejects a call before running it.
+const statusTooManyRequests = 429
+
+// isReadOnly reports whether command only reads state and may be sent again
+// even if the previous attempt might have reached the server.
+func isReadOnly(command string) bool {
+	command = strings.ToLower(command)
+	for _, prefix := range []string{"list", "query", "get"} {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryReason returns why a request failing with resp or err should be
+// retried, or "" when the failure is permanent.
+func retryReason(command string, resp *http.Response, err error) string {
+	if err != nil {
+		if neverSent(err) {
+			return err.Error()
+		}
+		// Anything else, even an EOF after the request was written, may
+		// have reached the server.
+		if !isReadOnly(command) {
+			return ""
+		}
+		if netErr, ok := err.(net.Error); ok && (netErr.Timeout() || netErr.Temporary()) {
+			return err.Error()
+		}
+		if isConnectionReset(err) {
+			return err.Error()
+		}
+		return ""
+	}
+
+	// A throttled call never ran. Any other failed call may have taken
+	// effect, e.g. when a proxy gave up waiting for the server, so only
+	// read-only calls are sent again.
+	if resp.StatusCode == statusTooManyRequests ||
+		retryableStatus[resp.StatusCode] && isReadOnly(command) {
+		return resp.Status
+	}
+	return ""
+}
+
+// neverSent reports whether err means the request never reached the server:
+// the connection could not be made or was refused.
+func neverSent(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	if !ok {
+		return false
+	}
+	if sysErr, ok := opErr.Err.(*os.SyscallError); ok && sysErr.Err == syscall.ECONNREFUSED {
+		return true
+	}
+	return opErr.Op == "dial"
+}
+
+func isConnectionReset(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	return err == syscall.ECONNRESET || strings.Contains(err.Error(), "connection reset") ||
+		strings.Contains(err.Error(), "EOF")
+}
+
+// retryDelay returns the jittered exponential backoff before attempt.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt-1)
+	if delay > retryMaxDelay || delay <= 0 {
+		delay = retryMaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryTransport sends a request again with exponential backoff when the
+// API fails with a transient error.
+type retryTransport struct {
+	maxRetries int
+	stop       <-chan struct{}
+	base       http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+	command := params.Get("command")
+
+	var body []byte
+	if req.Body != nil {
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp, err := t.base.RoundTrip(req)
+
+		reason := retryReason(command, resp, err)
+		if reason == "" || attempt >= t.maxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		delay := retryDelay(attempt + 1)
+		log.Printf("[WARN] Retrying %s in %s (%d/%d): %s",
+			command, delay, attempt+1, t.maxRetries, reason)
+
+		select {
+		case <-t.stop:
+			return nil, fmt.Errorf("Interrupted while retrying %s: %s", command, reason)
+		case <-time.After(delay):
+		}
+	}
+}
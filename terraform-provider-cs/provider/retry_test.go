@@ -0,0 +1,103 @@
+package cloudstack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+func TestRetryTransport(t *testing.T) {
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = time.Second }()
+
+	var calls int
+	status := 534
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"%sresponse": {"errorcode": %d, "errortext": "failed"}}`,
+				strings.ToLower(r.URL.Query().Get("command")), status)
+			return
+		}
+		fmt.Fprint(w, `{"listzonesresponse": {"count": 1, "zone": [{"id": "z1", "name": "zone1"}]}}`)
+	}))
+	defer server.Close()
+
+	config := testConfig(t, server.URL)
+	wrapTransport(config.client, func(base http.RoundTripper) http.RoundTripper {
+		return &retryTransport{maxRetries: 3, base: base}
+	})
+
+	zones, err := config.client.ListZones(cloudstack.NewListZonesParameter())
+	if err != nil {
+		t.Fatalf("ListZones failed after retries: %s", err)
+	}
+	if len(zones) != 1 || calls != 3 {
+		t.Errorf("got %d zones after %d calls, expected 1 zone after 3 calls", len(zones), calls)
+	}
+
+	for _, status = range []int{431, 530} {
+		calls = 0
+		if _, err := config.client.ListZones(cloudstack.NewListZonesParameter()); err == nil {
+			t.Errorf("expected error %d not to be retried", status)
+		}
+		if calls != 1 {
+			t.Errorf("error %d was sent %d times, expected once", status, calls)
+		}
+	}
+
+	// A call which may have taken effect is not sent again.
+	calls = 0
+	status = http.StatusGatewayTimeout
+	param := cloudstack.NewDeployVirtualMachineParameter("so", "tmpl", "zone")
+	if _, err := config.client.DeployVirtualMachine(param); err == nil {
+		t.Errorf("expected deployVirtualMachine to fail")
+	}
+	if calls != 1 {
+		t.Errorf("deployVirtualMachine was sent %d times, expected once", calls)
+	}
+
+	// A throttled call never ran, so it is sent again.
+	calls = 0
+	status = 429
+	config.client.DeployVirtualMachine(param)
+	if calls != 3 {
+		t.Errorf("throttled deployVirtualMachine was sent %d times, expected 3 times", calls)
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	refused := &net.OpError{Op: "read", Net: "tcp",
+		Err: &os.SyscallError{Syscall: "read", Err: syscall.ECONNREFUSED}}
+	dial := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no route to host")}
+
+	cases := []struct {
+		command string
+		err     error
+		retry   bool
+	}{
+		{"listZones", io.EOF, true},
+		{"listZones", io.ErrUnexpectedEOF, true},
+		{"deployVirtualMachine", io.EOF, false},
+		{"deployVirtualMachine", io.ErrUnexpectedEOF, false},
+		{"deployVirtualMachine", &url.Error{Op: "Get", URL: "http://cs", Err: dial}, true},
+		{"deployVirtualMachine", refused, true},
+	}
+	for _, c := range cases {
+		if retry := retryReason(c.command, nil, c.err) != ""; retry != c.retry {
+			t.Errorf("retryReason(%s, %v) retries: %t, expected %t", c.command, c.err, retry, c.retry)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cloudstack
+
+import (
+	"reflect"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// scope is the account, domain or project owning a resource.
+type scope struct {
+	account   string
+	domainId  string
+	projectId string
+}
+
+// withScope adds the account, domain_id and project_id arguments to the
+// schema of a resource.
+func withScope(s map[string]*schema.Schema) map[string]*schema.Schema {
+	for _, key := range []string{"account", "domain_id", "project_id"} {
+		s[key] = &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+			ForceNew: true,
+		}
+	}
+	return s
+}
+
+// withScopeFilters adds the account, domain_id and project_id arguments to
+// the schema of a data source, which searches the objects of that scope.
+func withScopeFilters(s map[string]*schema.Schema) map[string]*schema.Schema {
+	for _, key := range []string{"account", "domain_id", "project_id"} {
+		s[key] = &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			Computed: true,
+		}
+	}
+	return s
+}
+
+// getScope returns the scope of a resource. Arguments the resource doesn't
+// set, or doesn't have, fall back to the provider defaults.
+func getScope(d *schema.ResourceData, meta interface{}) scope {
+	config := meta.(*Config)
+
+	var s scope
+	if v, ok := d.GetOk("account"); ok {
+		s.account = v.(string)
+	}
+	if v, ok := d.GetOk("domain_id"); ok {
+		s.domainId = v.(string)
+	}
+	if v, ok := d.GetOk("project_id"); ok {
+		s.projectId = v.(string)
+	}
+	if s.account == "" && s.domainId == "" && s.projectId == "" {
+		s = scope{
+			account:   config.Account,
+			domainId:  config.DomainId,
+			projectId: config.ProjectId,
+		}
+	}
+	return s
+}
+
+// setParam sets the Account, DomainId and ProjectId fields of a create
+// parameter. Fields the command doesn't accept are skipped.
+func (s scope) setParam(param interface{}) {
+	if s.projectId != "" {
+		setField(param, "ProjectId", s.projectId)
+		return
+	}
+	if s.account != "" {
+		setField(param, "Account", s.account)
+	}
+	if s.domainId != "" {
+		setField(param, "DomainId", s.domainId)
+	}
+}
+
+// setListParam makes a list parameter find the resources of the scope. The
+// resources of a project are only listed with its projectid, and listall is
+// needed to see those of other accounts.
+func (s scope) setListParam(param interface{}) {
+	if s.projectId != "" {
+		setField(param, "ProjectId", s.projectId)
+		return
+	}
+	if s.account != "" {
+		setField(param, "ListAll", true)
+		setField(param, "Account", s.account)
+	}
+	if s.domainId != "" {
+		setField(param, "ListAll", true)
+		setField(param, "DomainId", s.domainId)
+	}
+}
+
+// readScope sets account, domain_id and project_id from the fields of obj
+// which has them.
+func readScope(d *schema.ResourceData, obj interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(obj))
+
+	if f := v.FieldByName("Account"); f.IsValid() {
+		d.Set("account", f.Interface().(cloudstack.NullString).String())
+	}
+	if f := v.FieldByName("DomainId"); f.IsValid() {
+		d.Set("domain_id", f.Interface().(cloudstack.ID).String())
+	}
+	if f := v.FieldByName("ProjectId"); f.IsValid() {
+		d.Set("project_id", f.Interface().(cloudstack.ID).String())
+	}
+}
+
+// setField calls Set on the field name of param, if param has it.
+func setField(param interface{}, name string, value interface{}) {
+	f := reflect.Indirect(reflect.ValueOf(param)).FieldByName(name)
+	if !f.IsValid() {
+		return
+	}
+	f.Addr().Interface().(interface {
+		Set(interface{}) error
+	}).Set(value)
+}
@@ -0,0 +1,148 @@
+package cloudstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sessionTransport authenticates the API calls of users without API keys
+// with a session opened by the login command. Every call carries the session
+// key and cookie, and the session is opened again when the server rejects
+// them because it expired.
+type sessionTransport struct {
+	endPoint *url.URL
+	username string
+	password string
+	domain   string
+	base     http.RoundTripper
+
+	mu         sync.Mutex
+	sessionKey string
+	cookies    []*http.Cookie
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := ""
+	for {
+		sessionKey, cookies, err := t.session(expired)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(sessionRequest(req, params, sessionKey, cookies))
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || expired != "" {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		log.Printf("[DEBUG] CloudStack session of %s expired, logging in again", t.username)
+		expired = sessionKey
+	}
+}
+
+// session returns the current session, logging in when there is none yet or
+// the current one is expired.
+func (t *sessionTransport) session(expired string) (string, []*http.Cookie, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessionKey == "" || t.sessionKey == expired {
+		if err := t.login(); err != nil {
+			return "", nil, err
+		}
+	}
+	return t.sessionKey, t.cookies, nil
+}
+
+func (t *sessionTransport) login() error {
+	params := url.Values{}
+	params.Set("command", "login")
+	params.Set("response", "json")
+	params.Set("username", t.username)
+	params.Set("password", t.password)
+	if t.domain != "" {
+		params.Set("domain", t.domain)
+	}
+
+	req, err := http.NewRequest("POST", t.endPoint.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("Error login as %s: %s", t.username, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("Error login as %s: %s", t.username, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Error login as %s: %s", t.username, parseErrorText(body))
+	}
+
+	var r struct {
+		LoginResponse struct {
+			SessionKey string `json:"sessionkey"`
+		} `json:"loginresponse"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil || r.LoginResponse.SessionKey == "" {
+		return fmt.Errorf("Error login as %s: unexpected response: %s", t.username, body)
+	}
+
+	log.Printf("[DEBUG] Logged in to CloudStack as %s", t.username)
+	t.sessionKey = r.LoginResponse.SessionKey
+	t.cookies = resp.Cookies()
+	return nil
+}
+
+// sessionRequest returns a copy of req authenticated by the session instead
+// of an API key signature.
+func sessionRequest(req *http.Request, params url.Values, sessionKey string, cookies []*http.Cookie) *http.Request {
+	values := url.Values{}
+	for k, vs := range params {
+		values[k] = vs
+	}
+	values.Del("apikey")
+	values.Del("signature")
+	values.Set("sessionkey", sessionKey)
+
+	r := new(http.Request)
+	*r = *req
+	r.URL = new(url.URL)
+	*r.URL = *req.URL
+	r.Header = http.Header{}
+	for k, vs := range req.Header {
+		if k != "Cookie" {
+			r.Header[k] = vs
+		}
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	if req.Method == "POST" {
+		body := values.Encode()
+		r.URL.RawQuery = ""
+		r.Body = ioutil.NopCloser(strings.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.GetBody = nil
+	} else {
+		r.URL.RawQuery = values.Encode()
+	}
+	return r
+}
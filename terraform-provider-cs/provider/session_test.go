@@ -0,0 +1,61 @@
+package cloudstack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+)
+
+func TestSessionLogin(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	config := &Config{
+		EndPoint: server.EndPoint(),
+		Username: server.Username,
+		Password: server.Password,
+		Domain:   "/",
+	}
+	if err := config.loadAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := config.client.ListZones(cloudstack.NewListZonesParameter()); err != nil {
+			t.Fatalf("listZones failed: %s", err)
+		}
+	}
+	if n := server.Calls("login"); n != 1 {
+		t.Errorf("login was called %d times, expected 1", n)
+	}
+
+	server.ExpireSessions()
+
+	zones, err := config.client.ListZones(cloudstack.NewListZonesParameter())
+	if err != nil {
+		t.Fatalf("listZones failed after the session expired: %s", err)
+	}
+	if len(zones) != 1 {
+		t.Errorf("listZones returned %d zones, expected 1", len(zones))
+	}
+	if n := server.Calls("login"); n != 2 {
+		t.Errorf("login was called %d times, expected 2", n)
+	}
+}
+
+func TestSessionLoginFailure(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	config := &Config{
+		EndPoint: server.EndPoint(),
+		Username: server.Username,
+		Password: "wrong",
+	}
+	err := config.loadAndValidate()
+	if err == nil || !strings.Contains(err.Error(), "Failed to authenticate user admin") {
+		t.Errorf("expected a login error, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package cloudstack
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimitTransport spaces out the requests sent to the API so that they do
+// not exceed the configured requests per second.
+type rateLimitTransport struct {
+	interval time.Duration
+	stop     <-chan struct{}
+	base     http.RoundTripper
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRateLimitTransport(requestsPerSecond float64, stop <-chan struct{}, base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		interval: time.Duration(float64(time.Second) / requestsPerSecond),
+		stop:     stop,
+		base:     base,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	t.next = t.next.Add(t.interval)
+	t.mu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-t.stop:
+			return nil, fmt.Errorf("Interrupted while waiting to send a request")
+		case <-time.After(wait):
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// asyncJobLimiter caps the number of async jobs the provider runs at once.
+// A command that may start a job takes a slot before it is sent. The slot is
+// given back right away when no job was started, or else once the job
+// finishes or the operation waiting for it gives up.
+type asyncJobLimiter struct {
+	slots chan struct{}
+	stop  <-chan struct{}
+
+	mu   sync.Mutex
+	jobs map[string]bool
+}
+
+func newAsyncJobLimiter(maxJobs int, stop <-chan struct{}) *asyncJobLimiter {
+	return &asyncJobLimiter{
+		slots: make(chan struct{}, maxJobs),
+		stop:  stop,
+		jobs:  map[string]bool{},
+	}
+}
+
+func (l *asyncJobLimiter) acquire(command string) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	log.Printf("[DEBUG] Waiting for a running async job to finish before %s", command)
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-l.stop:
+		return fmt.Errorf("Interrupted while waiting to send %s", command)
+	}
+}
+
+// hold keeps the slot taken by the command that started job id.
+func (l *asyncJobLimiter) hold(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.jobs[id] = true
+}
+
+// release gives back the slot held by job id, if any.
+func (l *asyncJobLimiter) release(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.jobs[id] {
+		delete(l.jobs, id)
+		<-l.slots
+	}
+}
+
+// asyncJobLimitTransport enforces an asyncJobLimiter on the API calls.
+type asyncJobLimitTransport struct {
+	limiter *asyncJobLimiter
+	base    http.RoundTripper
+}
+
+func (t *asyncJobLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+	command := params.Get("command")
+
+	if isReadOnly(command) {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || command != "queryAsyncJobResult" {
+			return resp, err
+		}
+
+		body, err := responseBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		if job := parseAsyncJob(body); job.JobStatus != 0 {
+			t.limiter.release(job.JobId)
+		}
+		return resp, nil
+	}
+
+	if err := t.limiter.acquire(command); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		<-t.limiter.slots
+		return nil, err
+	}
+
+	body, err := responseBody(resp)
+	if err != nil {
+		<-t.limiter.slots
+		return nil, err
+	}
+
+	if job := parseAsyncJob(body); job.JobId != "" {
+		t.limiter.hold(job.JobId)
+	} else {
+		<-t.limiter.slots
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,90 @@
+package cloudstack
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+func TestAsyncJobLimit(t *testing.T) {
+	var mu sync.Mutex
+	var jobs, running, maxRunning int
+	polls := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Query().Get("command") != "queryAsyncJobResult" {
+			jobs++
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			fmt.Fprintf(w, `{"deployvirtualmachineresponse": {"jobid": "job-%d"}}`, jobs)
+			return
+		}
+
+		id := r.URL.Query().Get("jobid")
+		polls[id]++
+		if polls[id] < 3 {
+			fmt.Fprintf(w, `{"queryasyncjobresultresponse": {"jobid": "%s", "jobstatus": 0}}`, id)
+			return
+		}
+		running--
+		fmt.Fprintf(w, `{"queryasyncjobresultresponse": {"jobid": "%s", "jobstatus": 1, `+
+			`"jobresult": {"virtualmachine": {"id": "vm-%s"}}}}`, id, id)
+	}))
+	defer server.Close()
+
+	config := testConfig(t, server.URL)
+	config.jobLimiter = newAsyncJobLimiter(1, nil)
+	wrapTransport(config.client, func(base http.RoundTripper) http.RoundTripper {
+		return &asyncJobLimitTransport{limiter: config.jobLimiter, base: base}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			param := cloudstack.NewDeployVirtualMachineParameter("so", "tmpl", "zone")
+			if _, err := config.client.DeployVirtualMachine(param); err != nil {
+				t.Errorf("DeployVirtualMachine failed: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if jobs != 3 || maxRunning != 1 {
+		t.Errorf("%d jobs ran with up to %d at once, expected 3 jobs one at a time",
+			jobs, maxRunning)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"listzonesresponse": {}}`)
+	}))
+	defer server.Close()
+
+	config := testConfig(t, server.URL)
+	wrapTransport(config.client, func(base http.RoundTripper) http.RoundTripper {
+		return newRateLimitTransport(20, nil, base)
+	})
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := config.client.ListZones(cloudstack.NewListZonesParameter()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("5 requests at 20/s took %s, expected at least 200ms", elapsed)
+	}
+}
@@ -0,0 +1,168 @@
+package cloudstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "<redacted>"
+
+// redactedParams lists the parameters never written to logs or traces.
+var redactedParams = map[string]bool{
+	"apikey":     true,
+	"signature":  true,
+	"secretkey":  true,
+	"password":   true,
+	"sessionkey": true,
+	"userdata":   true,
+	"publickey":  true,
+	"privatekey": true,
+}
+
+// redactParams flattens params and hides the values of credentials and
+// user data.
+func redactParams(params url.Values) map[string]string {
+	m := make(map[string]string, len(params))
+	for k, vs := range params {
+		name := strings.ToLower(k)
+		if redactedParams[name] || strings.Contains(name, "secret") ||
+			strings.Contains(name, "password") {
+			m[k] = redacted
+			continue
+		}
+		m[k] = strings.Join(vs, ",")
+	}
+	return m
+}
+
+// traceRecord is one API call as written to the trace file.
+type traceRecord struct {
+	Time      time.Time         `json:"time"`
+	Command   string            `json:"command"`
+	Params    map[string]string `json:"params"`
+	Status    int               `json:"status,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	LatencyMs float64           `json:"latency_ms"`
+	JobId     string            `json:"jobid,omitempty"`
+}
+
+func (r *traceRecord) String() string {
+	keys := make([]string, 0, len(r.Params))
+	for k := range r.Params {
+		if k != "command" && k != "response" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = fmt.Sprintf("%s=%s", k, r.Params[k])
+	}
+
+	s := fmt.Sprintf("%s(%s) status=%d latency=%.0fms",
+		r.Command, strings.Join(params, " "), r.Status, r.LatencyMs)
+	if r.JobId != "" {
+		s += " jobid=" + r.JobId
+	}
+	if r.Error != "" {
+		s += " error=" + r.Error
+	}
+	return s
+}
+
+// traceTransport logs every API call and, when a trace file is configured,
+// appends it to the file as a JSON line.
+type traceTransport struct {
+	base http.RoundTripper
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// close closes the trace file, after which calls are only logged.
+func (t *traceTransport) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c, ok := t.out.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("[WARN] Error close trace file: %s", err)
+		}
+	}
+	t.out = nil
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &traceRecord{
+		Time:    time.Now(),
+		Command: params.Get("command"),
+		Params:  redactParams(params),
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	record.LatencyMs = float64(time.Since(record.Time)) / float64(time.Millisecond)
+
+	if err != nil {
+		record.Error = err.Error()
+	} else {
+		record.Status = resp.StatusCode
+		body, err := responseBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		record.JobId = parseAsyncJob(body).JobId
+		if resp.StatusCode != http.StatusOK {
+			record.Error = parseErrorText(body)
+		}
+	}
+
+	log.Printf("[DEBUG] CloudStack API call: %s", record)
+	t.write(record)
+
+	return resp, err
+}
+
+func (t *traceTransport) write(record *traceRecord) {
+	b, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("[WARN] Error encode trace record: %s", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.out == nil {
+		return
+	}
+	if _, err := t.out.Write(append(b, '\n')); err != nil {
+		log.Printf("[WARN] Error write trace file: %s", err)
+	}
+}
+
+// parseErrorText returns the errortext of an API error response.
+func parseErrorText(body []byte) string {
+	var outer map[string]struct {
+		ErrorText string `json:"errortext"`
+	}
+	if err := json.Unmarshal(body, &outer); err != nil {
+		return ""
+	}
+	for _, inner := range outer {
+		return inner.ErrorText
+	}
+	return ""
+}
@@ -0,0 +1,115 @@
+package cloudstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/atsaki/golang-cloudstack-library"
+	"github.com/atsaki/terraform-provider-cloudstack/terraform-provider-cs/provider/cstest"
+)
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("command") == "queryAsyncJobResult" {
+			fmt.Fprint(w, `{"queryasyncjobresultresponse": {"jobid": "job-1", "jobstatus": 1, `+
+				`"jobresult": {"virtualmachine": {"id": "vm-1"}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{"deployvirtualmachineresponse": {"id": "vm-1", "jobid": "job-1"}}`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	config := testConfig(t, server.URL)
+	wrapTransport(config.client, func(base http.RoundTripper) http.RoundTripper {
+		return &traceTransport{base: base, out: &out}
+	})
+
+	param := cloudstack.NewDeployVirtualMachineParameter("so", "tmpl", "zone")
+	param.UserData.Set("c2VjcmV0")
+	if _, err := config.client.DeployVirtualMachine(param); err != nil {
+		t.Fatal(err)
+	}
+
+	var record traceRecord
+	if err := json.Unmarshal(bytes.SplitN(out.Bytes(), []byte("\n"), 2)[0], &record); err != nil {
+		t.Fatalf("invalid trace line %q: %s", out.String(), err)
+	}
+
+	if record.Command != "deployVirtualMachine" || record.Status != 200 || record.JobId != "job-1" {
+		t.Errorf("unexpected trace record: %+v", record)
+	}
+	for _, k := range []string{"apikey", "signature", "userdata"} {
+		if record.Params[k] != redacted {
+			t.Errorf("%s is %q, expected it to be redacted", k, record.Params[k])
+		}
+	}
+	if record.Params["zoneid"] != "zone" {
+		t.Errorf("zoneid is %q, expected zone", record.Params["zoneid"])
+	}
+}
+
+func TestTraceFileSession(t *testing.T) {
+	server := cstest.NewServer()
+	defer server.Close()
+
+	f, err := ioutil.TempFile("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	stop := make(chan struct{})
+	config := &Config{
+		EndPoint:  server.EndPoint(),
+		Username:  server.Username,
+		Password:  server.Password,
+		TraceFile: f.Name(),
+		stop:      stop,
+	}
+	if err := config.loadAndValidate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The trace file is closed once terraform stops the provider.
+	close(stop)
+	time.Sleep(10 * time.Millisecond)
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []traceRecord
+	for _, line := range bytes.Split(bytes.TrimSpace(b), []byte("\n")) {
+		var record traceRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid trace line %q: %s", line, err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 2 || records[0].Command != "login" || records[1].Command != "listCapabilities" {
+		t.Fatalf("expected login and listCapabilities to be traced, got %+v", records)
+	}
+	if records[0].Params["password"] != redacted {
+		t.Errorf("password is %q, expected it to be redacted", records[0].Params["password"])
+	}
+	if records[1].Params["sessionkey"] != redacted {
+		t.Errorf("sessionkey is %q, expected it to be redacted", records[1].Params["sessionkey"])
+	}
+
+	if _, err := config.client.ListZones(cloudstack.NewListZonesParameter()); err != nil {
+		t.Fatal(err)
+	}
+	if b2, _ := ioutil.ReadFile(f.Name()); !bytes.Equal(b, b2) {
+		t.Errorf("calls were traced after the provider stopped")
+	}
+}
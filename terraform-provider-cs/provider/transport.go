@@ -0,0 +1,95 @@
+package cloudstack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/atsaki/golang-cloudstack-library"
+)
+
+// requestParams returns the API parameters of req. The client may send them
+// either in the query string or as a form encoded body, so the body is read
+// and put back for the next transport.
+func requestParams(req *http.Request) (url.Values, error) {
+	params := req.URL.Query()
+	if req.Method != "POST" || req.Body == nil {
+		return params, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return params, nil
+	}
+	for k, vs := range form {
+		params[k] = append(params[k], vs...)
+	}
+	return params, nil
+}
+
+// responseBody reads the body of resp and puts it back so that the client
+// can still decode it.
+func responseBody(resp *http.Response) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// asyncJobResponse holds the fields of an API response that describe an
+// async job. Both the response of an async command and the response of
+// queryAsyncJobResult carry the jobid, and the response of a command
+// creating an object also carries the id of the object.
+type asyncJobResponse struct {
+	JobId     string `json:"jobid"`
+	JobStatus int    `json:"jobstatus"`
+	Id        string `json:"id"`
+}
+
+// parseAsyncJob extracts the async job fields from a response like
+// {"deployvirtualmachineresponse": {"jobid": "...", "id": "..."}}.
+func parseAsyncJob(body []byte) asyncJobResponse {
+	var job asyncJobResponse
+
+	var outer map[string]json.RawMessage
+	if err := json.Unmarshal(body, &outer); err != nil {
+		return job
+	}
+	for _, inner := range outer {
+		json.Unmarshal(inner, &job)
+	}
+	return job
+}
+
+// baseTransport returns the transport used by client, falling back to
+// http.DefaultTransport like http.Client does.
+func baseTransport(client *http.Client) http.RoundTripper {
+	if client == nil || client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return client.Transport
+}
+
+// wrapTransport puts the transport returned by wrap in front of the one used
+// by client. The http.Client is copied so that clients sharing it are not
+// affected.
+func wrapTransport(client *cloudstack.Client, wrap func(http.RoundTripper) http.RoundTripper) {
+	httpClient := http.Client{}
+	if client.HTTPClient != nil {
+		httpClient = *client.HTTPClient
+	}
+	httpClient.Transport = wrap(baseTransport(client.HTTPClient))
+	client.HTTPClient = &httpClient
+}
@@ -3,7 +3,6 @@ package cloudstack
 import (
 	"fmt"
 	"reflect"
-	"strings"
 
 	"github.com/atsaki/golang-cloudstack-library"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -26,29 +25,6 @@ func equalName(obj interface{}, name string) bool {
 	return objName == name
 }
 
-func getObjectId(objs []interface{}) (string, error) {
-	idset := map[string]bool{}
-	for _, obj := range objs {
-		// type of v is expected to be pointer to resource
-		v := reflect.ValueOf(obj)
-		id := v.Elem().FieldByName("Id").Interface().(cloudstack.ID).String()
-		idset[id] = true
-	}
-
-	ids := []string{}
-	for id := range idset {
-		ids = append(ids, id)
-	}
-	if len(ids) == 0 {
-		return "", fmt.Errorf("getObjectId: No ID associated")
-	}
-	if len(ids) > 1 {
-		return "", fmt.Errorf("getObjectId: Multiple IDs associated. %v", ids)
-	}
-
-	return ids[0], nil
-}
-
 func toInterfaceSlice(objs interface{}) []interface{} {
 	v := reflect.ValueOf(objs)
 	slice := make([]interface{}, v.Len())
@@ -59,86 +35,54 @@ func toInterfaceSlice(objs interface{}) []interface{} {
 	return slice
 }
 
-func nameToID(client *cloudstack.Client, resourcetype, name string) (id string, err error) {
-
-	resourcetype = strings.ToLower(resourcetype)
+// getResourceId returns the id given by the <resourcetype>_id or
+// <resourcetype>_name argument. Both accept either an id or a name.
+func getResourceId(d *schema.ResourceData, meta interface{}, resourcetype string) (id string, err error) {
 
-	fn := func(obj interface{}) bool {
-		return equalName(obj, name)
+	config := meta.(*Config)
+	l, err := getLookup(d, meta, resourcetype)
+	if err != nil {
+		return "", err
 	}
 
-	var objs interface{}
-	switch resourcetype {
-	case "zone":
-		param := cloudstack.NewListZonesParameter()
-		param.Name.Set(name)
-		objs, err = client.ListZones(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list zone '%s': %s", name, err)
-		}
-	case "service_offering":
-		param := cloudstack.NewListServiceOfferingsParameter()
-		param.Name.Set(name)
-		objs, err = client.ListServiceOfferings(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list serviceoffering '%s': %s", name, err)
-		}
-	case "network_offering":
-		param := cloudstack.NewListNetworkOfferingsParameter()
-		param.Name.Set(name)
-		objs, err = client.ListNetworkOfferings(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list serviceoffering '%s': %s", name, err)
-		}
-	case "disk_offering":
-		param := cloudstack.NewListDiskOfferingsParameter()
-		param.Name.Set(name)
-		objs, err = client.ListDiskOfferings(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list diskoffering '%s': %s", name, err)
-		}
-	case "template":
-		param := cloudstack.NewListTemplatesParameter("executable")
-		objs, err = client.ListTemplates(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list template '%s': %s", name, err)
-		}
-	case "network":
-		param := cloudstack.NewListNetworksParameter()
-		objs, err = client.ListNetworks(param)
-		if err != nil {
-			return "", fmt.Errorf("Failed to list network '%s': %s", name, err)
-		}
-	default:
-		return "", fmt.Errorf("Can't convert name of %s to id", resourcetype)
+	if tmpId, ok := d.GetOk(fmt.Sprintf("%s_id", resourcetype)); ok {
+		id = tmpId.(string)
+		return nameToID(config.client, resourcetype, id, l)
 	}
 
-	id, err = getObjectId(filter(toInterfaceSlice(objs), fn).([]interface{}))
-	if err != nil {
-		return "", fmt.Errorf("Faild to get %s id from %s: %s", resourcetype, name, err)
+	tmpName, ok := d.GetOk(fmt.Sprintf("%s_name", resourcetype))
+	if !ok {
+		return "", fmt.Errorf("%s_id and %s_name are not specified",
+			resourcetype, resourcetype)
 	}
-	return id, nil
+	return nameToID(config.client, resourcetype, tmpName.(string), l)
 }
 
-func getResourceId(d *schema.ResourceData, meta interface{}, resourcetype string) (id string, err error) {
+// getLookup returns what narrows down the names given to resource d: its
+// scope and, for templates and ISOs, its zone, template_filter and
+// hypervisor.
+func getLookup(d *schema.ResourceData, meta interface{}, resourcetype string) (lookup, error) {
+	l := lookup{scope: getScope(d, meta)}
+	if resourcetype != "template" && resourcetype != "iso" {
+		return l, nil
+	}
 
-	var ok bool
-	config := meta.(*Config)
-	tmpId, ok := d.GetOk(fmt.Sprintf("%s_id", resourcetype))
-	if ok {
-		id = tmpId.(string)
-	} else {
-		tmpName, ok := d.GetOk(fmt.Sprintf("%s_name", resourcetype))
-		if !ok {
-			return "", fmt.Errorf("%s_id and %s_name are not specified",
-				resourcetype, resourcetype)
-		}
-		id, err = nameToID(config.client, resourcetype, tmpName.(string))
+	_, hasZoneId := d.GetOk("zone_id")
+	_, hasZoneName := d.GetOk("zone_name")
+	if hasZoneId || hasZoneName {
+		zoneId, err := getResourceId(d, meta, "zone")
 		if err != nil {
-			return "", err
+			return l, err
 		}
+		l.zoneId = zoneId
+	}
+	if v, ok := d.GetOk("template_filter"); ok {
+		l.templateFilter = v.(string)
+	}
+	if v, ok := d.GetOk("hypervisor"); ok {
+		l.hypervisor = v.(string)
 	}
-	return id, nil
+	return l, nil
 }
 
 func filter(xs interface{}, fn func(interface{}) bool) interface{} {
@@ -155,3 +99,21 @@ func filter(xs interface{}, fn func(interface{}) bool) interface{} {
 	}
 	return ys.Interface()
 }
+
+// toStringMap converts the value of a TypeMap argument.
+func toStringMap(m map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		result[k] = v.(string)
+	}
+	return result
+}
+
+// toStrings converts the elements of a set or list of strings.
+func toStrings(l []interface{}) []string {
+	result := make([]string, len(l))
+	for i, v := range l {
+		result[i] = v.(string)
+	}
+	return result
+}